@@ -0,0 +1,211 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// The authentication methods that may be recorded in Config.AuthMethod.
+const (
+	AuthMethodBasic  = "basic"
+	AuthMethodPKI    = "pki"
+	AuthMethodPKCS12 = "pkcs12"
+	AuthMethodToken  = "token"
+)
+
+// An Authenticator adds credentials to each request made by a Grid client.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+/*
+A TLSAuthenticator is an Authenticator that presents its credentials during
+the TLS handshake, rather than (or as well as) on each request.
+*/
+type TLSAuthenticator interface {
+	Authenticator
+	ConfigureTLS(config *tls.Config) error
+}
+
+// BasicAuth authenticates with a GRiD username and password.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authenticate sets the request's Authorization header.
+func (a *BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerToken authenticates with a bearer token.
+type BearerToken struct {
+	Token string
+}
+
+// Authenticate sets the request's Authorization header.
+func (a *BearerToken) Authenticate(req *http.Request) error {
+	if a.Token == "" {
+		return errors.New("bearer token is empty")
+	}
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// ClientCertificate authenticates with a PKI client certificate, such as one
+// issued for a CAC, using mutual TLS.
+type ClientCertificate struct {
+	Certificate tls.Certificate
+}
+
+// Authenticate does nothing, as the certificate is presented during the TLS
+// handshake.
+func (a *ClientCertificate) Authenticate(req *http.Request) error {
+	return nil
+}
+
+// ConfigureTLS adds the certificate to config.
+func (a *ClientCertificate) ConfigureTLS(config *tls.Config) error {
+	config.Certificates = []tls.Certificate{a.Certificate}
+	return nil
+}
+
+// LoadClientCertificate reads a client certificate and its private key from a
+// pair of PEM-encoded files.
+func LoadClientCertificate(certFile, keyFile string) (*ClientCertificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientCertificate{Certificate: cert}, nil
+}
+
+/*
+LoadPKCS12Certificate reads a client certificate and its private key from a
+password-protected PKCS#12 (.p12 or .pfx) file. Any other certificates in the
+file, such as the intermediate CAs included in CAC and DoD exports, are sent
+along with the client certificate as its chain.
+*/
+func LoadPKCS12Certificate(file, password string) (*ClientCertificate, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("reading PKCS#12 file %v: %v", file, err)
+	}
+
+	var key []byte
+	var certs []*pem.Block
+	for _, b := range blocks {
+		// drop the bag attributes, which are not valid in a key pair
+		b = &pem.Block{Type: b.Type, Bytes: b.Bytes}
+		if b.Type == "CERTIFICATE" {
+			certs = append(certs, b)
+		} else {
+			key = pem.EncodeToMemory(b)
+		}
+	}
+	if key == nil || len(certs) == 0 {
+		return nil, fmt.Errorf("reading PKCS#12 file %v: expected a private key and a certificate", file)
+	}
+
+	// the file does not say which certificate is the key's, so try each as
+	// the leaf, followed by the others
+	for i := range certs {
+		var chain []byte
+		chain = append(chain, pem.EncodeToMemory(certs[i])...)
+		for j, c := range certs {
+			if j != i {
+				chain = append(chain, pem.EncodeToMemory(c)...)
+			}
+		}
+		if cert, err := tls.X509KeyPair(chain, key); err == nil {
+			cert.Leaf, _ = x509.ParseCertificate(cert.Certificate[0])
+			return &ClientCertificate{Certificate: cert}, nil
+		}
+	}
+	return nil, fmt.Errorf("reading PKCS#12 file %v: no certificate matches the private key", file)
+}
+
+// WithAuthenticator authenticates requests using a.
+func WithAuthenticator(a Authenticator) Option {
+	return func(g *Grid) error {
+		if a == nil {
+			return errors.New("authenticator must be non-nil")
+		}
+		if ta, ok := a.(TLSAuthenticator); ok {
+			g.configureTLS(ta.ConfigureTLS)
+		}
+		g.Authenticator = a
+		return nil
+	}
+}
+
+// WithBearerToken authenticates requests with a bearer token.
+func WithBearerToken(token string) Option {
+	return WithAuthenticator(&BearerToken{Token: token})
+}
+
+// WithClientCertificate authenticates using the PEM-encoded client
+// certificate and private key in certFile and keyFile.
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(g *Grid) error {
+		cert, err := LoadClientCertificate(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		return WithAuthenticator(cert)(g)
+	}
+}
+
+// WithPKCS12 authenticates using the client certificate and private key in a
+// PKCS#12 file.
+func WithPKCS12(file, password string) Option {
+	return func(g *Grid) error {
+		cert, err := LoadPKCS12Certificate(file, password)
+		if err != nil {
+			return err
+		}
+		return WithAuthenticator(cert)(g)
+	}
+}
+
+// configAuthOption returns the option for the authentication method recorded
+// in config.
+func configAuthOption(config Config) (Option, error) {
+	switch config.AuthMethod {
+	case "", AuthMethodBasic:
+		return withAuth(config.Auth), nil
+	case AuthMethodPKI:
+		return WithClientCertificate(config.CertFile, config.KeyFile), nil
+	case AuthMethodPKCS12:
+		return WithPKCS12(config.CertFile, config.CertPassword), nil
+	case AuthMethodToken:
+		return WithBearerToken(config.Token), nil
+	}
+	return nil, fmt.Errorf("unknown authentication method %q", config.AuthMethod)
+}
@@ -0,0 +1,218 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeClientCertificate creates a CA and a client certificate signed by it,
+// writes the client certificate and key as PEM files, and returns their paths
+// along with the CA.
+func writeClientCertificate(t *testing.T) (certFile, keyFile string, ca *x509.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ = x509.ParseCertificate(caDER)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "SMITH.JOHN.1234567890"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client.key")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile, ca
+}
+
+func TestClientCertificateAuth(t *testing.T) {
+	certFile, keyFile, ca := writeClientCertificate(t)
+
+	var subject string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.Write([]byte(`{"task_id": "abc"}`))
+	}))
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	ts.StartTLS()
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600)
+
+	// without a client certificate the handshake fails
+	g, err := NewClient(WithBaseURL(ts.URL), WithCAFile(caFile), WithRetryPolicy(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.TaskDetails("abc"); err == nil {
+		t.Error("Expected the server to require a client certificate")
+	}
+
+	// the order of the TLS options does not matter
+	g, err = NewClient(WithBaseURL(ts.URL), WithClientCertificate(certFile, keyFile), WithCAFile(caFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.TaskDetails("abc"); err != nil {
+		t.Fatal(err)
+	}
+	if subject != "SMITH.JOHN.1234567890" {
+		t.Errorf("Unexpected client certificate subject %q", subject)
+	}
+}
+
+func TestPKCS12ChainAuth(t *testing.T) {
+	// client_chain.p12 holds a client certificate issued by an intermediate
+	// CA, and the intermediate and root CAs, with the password "password"
+	root, err := ioutil.ReadFile(filepath.Join("testdata", "client_chain_root.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := LoadPKCS12Certificate(filepath.Join("testdata", "client_chain.p12"), "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Certificate.Leaf.Subject.CommonName != "SMITH.JOHN.1234567890" || len(cert.Certificate.Certificate) != 3 {
+		t.Errorf("Expected the client certificate and its chain, got %v with %v certificates",
+			cert.Certificate.Leaf.Subject, len(cert.Certificate.Certificate))
+	}
+
+	var subject string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.Write([]byte(`{"task_id": "abc"}`))
+	}))
+	// the server trusts only the root, so the client must send the
+	// intermediate for its certificate to be verified
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(root)
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	ts.StartTLS()
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600)
+
+	g, err := NewClient(WithBaseURL(ts.URL), WithCAFile(caFile), WithPKCS12(filepath.Join("testdata", "client_chain.p12"), "password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.TaskDetails("abc"); err != nil {
+		t.Fatal(err)
+	}
+	if subject != "SMITH.JOHN.1234567890" {
+		t.Errorf("Unexpected client certificate subject %q", subject)
+	}
+
+	if _, err := LoadPKCS12Certificate(filepath.Join("testdata", "client_chain.p12"), "wrong"); err == nil {
+		t.Error("Expected a wrong password to be rejected")
+	}
+}
+
+func TestLoadPKCS12CertificateInvalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "client.p12")
+	ioutil.WriteFile(file, []byte("not a PKCS#12 file"), 0600)
+	if _, err := LoadPKCS12Certificate(file, "password"); err == nil {
+		t.Error("Should have received error")
+	}
+}
+
+func TestHeaderAuthenticators(t *testing.T) {
+	var auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		option Option
+		want   string
+	}{
+		{WithBasicAuth("johnsmith", "password"), "Basic am9obnNtaXRoOnBhc3N3b3Jk"},
+		{withAuth("am9obnNtaXRoOnBhc3N3b3Jk"), "Basic am9obnNtaXRoOnBhc3N3b3Jk"},
+		{WithBearerToken("s3cr3t"), "Bearer s3cr3t"},
+	}
+	for _, tt := range tests {
+		g, err := NewClient(WithBaseURL(ts.URL), tt.option)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := g.TaskDetails("abc"); err != nil {
+			t.Fatal(err)
+		}
+		if auth != tt.want {
+			t.Errorf("Expected Authorization %q, got %q", tt.want, auth)
+		}
+	}
+}
+
+func TestConfigAuthOption(t *testing.T) {
+	if _, err := configAuthOption(Config{AuthMethod: "kerberos"}); err == nil {
+		t.Error("Should have received error")
+	}
+	if _, err := configAuthOption(Config{AuthMethod: AuthMethodToken, Token: "s3cr3t"}); err != nil {
+		t.Error(err)
+	}
+}
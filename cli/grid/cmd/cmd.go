@@ -33,6 +33,13 @@ grid is a command-line interface to the GRiD database.`,
 
 var g *grid.Grid
 
+// debug enables tracing of every GRiD request and response to stderr.
+var debug bool
+
+func init() {
+	GridCmd.PersistentFlags().BoolVar(&debug, "debug", false, "dump each GRiD request and response to stderr")
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number of the GRiD CLI",
@@ -50,8 +57,10 @@ func Execute() {
 	GridCmd.AddCommand(exportCmd)
 	GridCmd.AddCommand(lookupCmd)
 	GridCmd.AddCommand(lsCmd)
+	GridCmd.AddCommand(presetCmd)
 	GridCmd.AddCommand(pullCmd)
 	GridCmd.AddCommand(taskCmd)
+	GridCmd.AddCommand(verifyCmd)
 	GridCmd.AddCommand(versionCmd)
 
 	if err := GridCmd.Execute(); err != nil {
@@ -63,12 +72,18 @@ func Execute() {
 // initClient is called by each subcommand except configure. The reason is
 // simple. Configure can proceed without a valid client, and in fact is a
 // prerequisite to any other API call. If this weren't the case, it would be an
-// init() function.
-func initClient() error {
+// init() function. Any options are passed on to grid.New.
+func initClient(opts ...grid.Option) error {
 	var err error
-	g, err = grid.New()
+	g, err = grid.New(opts...)
 	if err != nil {
-		return errors.New("It looks like this is your first time running the GRiD CLI.\nPlease run 'grid configure' to continue.")
+		if os.IsNotExist(err) {
+			return errors.New("It looks like this is your first time running the GRiD CLI.\nPlease run 'grid configure' to continue.")
+		}
+		return err
+	}
+	if debug {
+		g.Use(grid.TraceMiddleware(os.Stderr))
 	}
 	return nil
 }
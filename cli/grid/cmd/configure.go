@@ -18,9 +18,11 @@ import (
 	"bufio"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/howeyc/gopass"
@@ -50,19 +52,83 @@ func readPassword(prompt string) (passwd string, err error) {
 	return strings.TrimSpace(string(password)), nil
 }
 
+// readPath prompts for the path of an existing file, and returns it made
+// absolute, so that the config file works from any directory.
+func readPath(prompt string) (string, error) {
+	path, err := readLine(prompt)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", errors.New("Please provide a file")
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		return "", fmt.Errorf("%v is a directory", path)
+	}
+	return path, nil
+}
+
 /*
 logon is called whenever all fields of the config file need to be updated, or
 or upon config file creation.
 */
 func logon() error {
-	username, err := readLine("GRiD Username: ")
+	// keep any TLS settings from an existing config file
+	config, _ := grid.GetConfig()
+
+	method, err := readLine("Authentication method (basic, pki, pkcs12, token) [basic]: ")
 	if err != nil {
 		return err
 	}
+	if method == "" {
+		method = grid.AuthMethodBasic
+	}
 
-	password, err := readPassword("GRiD Password: ")
-	if err != nil {
-		return err
+	// forget the credentials of any previous method
+	config.AuthMethod = method
+	config.Auth, config.Token = "", ""
+	config.CertFile, config.KeyFile, config.CertPassword = "", "", ""
+
+	switch method {
+	case grid.AuthMethodBasic:
+		username, err := readLine("GRiD Username: ")
+		if err != nil {
+			return err
+		}
+
+		password, err := readPassword("GRiD Password: ")
+		if err != nil {
+			return err
+		}
+
+		config.Auth = base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	case grid.AuthMethodPKI:
+		if config.CertFile, err = readPath("Client Certificate (PEM): "); err != nil {
+			return err
+		}
+		if config.KeyFile, err = readPath("Client Private Key (PEM): "); err != nil {
+			return err
+		}
+	case grid.AuthMethodPKCS12:
+		if config.CertFile, err = readPath("Client Certificate (PKCS#12): "); err != nil {
+			return err
+		}
+		if config.CertPassword, err = readPassword("Certificate Password: "); err != nil {
+			return err
+		}
+	case grid.AuthMethodToken:
+		if config.Token, err = readPassword("GRiD Token: "); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unknown authentication method %q", method)
 	}
 
 	baseURL, err := readLine("GRiD Base URL: ")
@@ -70,59 +136,76 @@ func logon() error {
 		return err
 	}
 
+	config.URL = baseURL
+	if caFile != "" {
+		config.CAFile = caFile
+	}
+
+	return writeConfig(config)
+}
+
+// writeConfig encodes the configuration details as JSON, overwriting the
+// config file.
+func writeConfig(config grid.Config) error {
 	file, err := grid.CreateConfigFile()
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
-
-	// encode the configuration details as JSON
-	config := grid.Config{Auth: auth, URL: baseURL}
-	json.NewEncoder(file).Encode(config)
-
-	return nil
+	return json.NewEncoder(file).Encode(config)
 }
 
-// updateBaseURL rewrites the config file, updating only the base URL.
-func updateBaseURL(baseURL string) {
+// updateConfig rewrites the config file, updating only the base URL and CA
+// file that were given on the command line.
+func updateConfig() {
 	cfg, err := grid.GetConfig()
 	if err != nil {
 		err := logon()
 		if err != nil {
 			panic(err)
 		}
-	} else {
-		file, err := grid.CreateConfigFile()
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer file.Close()
+		return
+	}
 
-		// encode the configuration details as JSON
-		config := grid.Config{Auth: cfg.Auth, URL: baseURL}
-		json.NewEncoder(file).Encode(config)
+	if baseURL != "" {
+		cfg.URL = baseURL
+	}
+	if caFile != "" {
+		cfg.CAFile = caFile
+	}
+	if err := writeConfig(cfg); err != nil {
+		log.Fatal(err)
 	}
 }
 
-var baseURL string
+var baseURL, caFile string
 
 func init() {
 	configureCmd.Flags().StringVarP(&baseURL, "base_url", "b", "", "GRiD Base URL")
+	configureCmd.Flags().StringVarP(&caFile, "ca_file", "c", "", "PEM bundle of CA certificates to trust (e.g., DoD root CAs)")
 }
 
 var configureCmd = &cobra.Command{
-	Use:   "configure [-b base_url]",
+	Use:   "configure [-b base_url] [-c ca_file]",
 	Short: "Configure the CLI",
 	Long: `
 Configure the GRiD CLI with the user's GRiD credentials.
 
-This function will prompt the user for their GRiD username and password, which
-is encoded in the user's config.json file`,
+This function will prompt the user for an authentication method and the
+matching credentials, which are recorded in the user's config.json file:
+
+  basic    GRiD username and password
+  pki      PEM-encoded client certificate and private key (e.g., CAC/mTLS)
+  pkcs12   PKCS#12 (.p12/.pfx) client certificate and its password
+  token    bearer token
+
+If the GRiD server's certificate is issued by a CA that is not trusted by the
+operating system, such as the DoD root CAs, use -c to record the path to a PEM
+bundle of those CA certificates.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if baseURL != "" {
-			updateBaseURL(baseURL)
+		if baseURL != "" || caFile != "" {
+			updateConfig()
 		} else {
 			err := logon()
 			if err != nil {
@@ -15,35 +15,121 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/venicegeo/grid-sdk-go"
 )
 
+var (
+	parallel  int
+	outputDir string
+	manifest  bool
+	exportPk  int
+	unzip     bool
+)
+
+func init() {
+	pullCmd.Flags().IntVarP(&parallel, "parallel", "p", 4, "number of files to download at once")
+	pullCmd.Flags().StringVarP(&outputDir, "output-dir", "o", ".", "directory to save the files in")
+	pullCmd.Flags().BoolVar(&manifest, "sha256", false, "record the SHA-256 checksum of each file in "+grid.ManifestName)
+	pullCmd.Flags().IntVarP(&exportPk, "export", "e", 0, "download every file of the export with this primary key")
+	pullCmd.Flags().BoolVar(&unzip, "unzip", false, "unpack downloaded zip files")
+}
+
 var pullCmd = &cobra.Command{
-	Use:   "pull [pk...]",
+	Use:   "pull [-p N] [-o dir] [--sha256] [--unzip] [pk... | --export pk]",
 	Short: "Download File",
 	Long: `
-Download the file(s) specified by the given primary key(s).`,
+Download the file(s) specified by the given primary key(s), or with --export,
+every file of the given export into a directory named for the export.
+
+Files are downloaded in parallel. If a download fails, the others continue, and
+running the same command again resumes any partial downloads.
+
+Each file is checked against any size and checksum given by GRiD. With
+--sha256, the checksums are also recorded in the output directory, to be
+checked later with 'grid verify'.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		err := initClient()
+		var opts []grid.Option
+		if manifest {
+			opts = append(opts, grid.WithSHA256Manifest())
+		}
+		err := initClient(opts...)
 		if err != nil {
 			fmt.Println(err.Error())
 			return
 		}
 
+		var pks []int
 		for _, arg := range args {
 			pk, err := strconv.Atoi(arg)
 			if err != nil {
 				fmt.Printf("Error parsing \"%v\". Please provide primary keys as integers.\n\n", arg) // Continuing with remaining keys...\n\n", arg)
 				continue
 			}
-			_, err = g.DownloadByPk(pk)
-			if err != nil {
-				log.Fatal(err.Error())
-			}
+			pks = append(pks, pk)
+		}
+
+		// stop downloading on Ctrl-C, keeping the partial files
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if !download(ctx, pks, exportPk) {
+			os.Exit(1)
 		}
 	},
 }
+
+/*
+download downloads the files with the given primary keys, or if exportPk is
+non-zero, every file of that export, as configured by the pull flags. It shows
+a progress bar while downloading, then prints the outcome for each file, and
+reports whether every file was downloaded.
+*/
+func download(ctx context.Context, pks []int, exportPk int) bool {
+	bar := newProgressBar(os.Stderr, len(pks))
+	m := &grid.DownloadManager{
+		Grid:            g,
+		Dir:             outputDir,
+		Workers:         parallel,
+		ContinueOnError: true,
+		Unzip:           unzip,
+		TotalProgress:   bar.update,
+	}
+	var results []grid.DownloadResult
+	var err error
+	if exportPk != 0 {
+		var dl *grid.ExportDownload
+		dl, err = m.DownloadExport(ctx, exportPk)
+		if dl == nil {
+			bar.finish()
+			fmt.Println(err)
+			return false
+		}
+		results = dl.Results
+	} else {
+		results, err = m.Download(ctx, pks)
+	}
+	bar.finish()
+
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			fmt.Printf("Failed to download %v: %v\n", r.Pk, r.Err)
+		case r.Info.Resumed > 0:
+			fmt.Printf("Downloaded %v (%v bytes, resumed from byte %v)\n", r.Info.Path, r.Info.Size, r.Info.Resumed)
+		default:
+			fmt.Printf("Downloaded %v (%v bytes)\n", r.Info.Path, r.Info.Size)
+		}
+		if r.Unzipped != "" {
+			fmt.Printf("Unzipped %v into %v\n", r.Info.Path, r.Unzipped)
+		}
+	}
+	return err == nil
+}
@@ -15,20 +15,60 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+	"github.com/venicegeo/grid-sdk-go"
 )
 
+var wait, pull, raster bool
+
+func init() {
+	exportCmd.Flags().BoolVarP(&raster, "raster", "r", false, "export raster collects instead of point cloud collects")
+	exportCmd.Flags().BoolVarP(&wait, "wait", "w", false, "wait for the export to finish")
+	exportCmd.Flags().BoolVar(&pull, "pull", false, "download the export's files once it finishes (implies --wait)")
+	exportCmd.Flags().IntVarP(&parallel, "parallel", "p", 4, "number of files to download at once with --pull")
+	exportCmd.Flags().StringVarP(&outputDir, "output-dir", "o", ".", "directory to save the export in with --pull")
+	exportCmd.Flags().BoolVar(&unzip, "unzip", false, "unpack downloaded zip files with --pull")
+}
+
 var exportCmd = &cobra.Command{
-	Use:   "export [AOI] [Collects]...",
+	Use:   "export [AOI] [Collects]... [-r] [-w] [--pull [-o dir]] [options]",
 	Short: "Initiate a GRiD Export",
 	Long: `
-Export is used to initiate a GRiD export for the AOI and for each of the provided collects.`,
+Export is used to initiate a GRiD export for the AOI and for each of the provided collects.
+
+The collects are point cloud collects, unless --raster is given, in which case
+they are raster collects. Raster exports take --raster-format and --resampling,
+and of the point cloud options only --hsrs, --file-export-options, --compressed
+and --send-email.
+
+With --wait, export waits for the export task to finish, printing each change
+in its state, and exits with a non-zero status if the task fails. With --pull,
+it then downloads the export's files into a directory named for the export, as
+by 'grid pull --export'.
+
+The point cloud export options may be given as flags, named for the GRiD
+parameters they set, or read from a YAML or JSON file with --options-file,
+keyed by the same names with underscores:
+
+  intensity: true
+  file_export_format: las14
+  pcl_terrain: urban
+
+With --preset, the options start from a preset saved by 'grid preset save'
+instead of the defaults. Flags given override the file, which overrides the
+preset. With --dry-run,
+export prints the request it would send, with the API key redacted, and exits.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := initClient()
 		if err != nil {
@@ -55,7 +95,36 @@ Export is used to initiate a GRiD export for the AOI and for each of the provide
 			return
 		}
 
-		export, _, err := g.GeneratePointCloudExport(pk, collects, nil)
+		var options *grid.GeneratePointCloudExportOptions
+		var rasterOptions *grid.GenerateRasterExportOptions
+		if raster {
+			rasterOptions, err = rasterExportOptions(cmd)
+		} else {
+			options, err = exportOptions(cmd)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if dryRun {
+			var req *http.Request
+			if raster {
+				req, err = g.NewGenerateRasterExportRequest(context.Background(), pk, collects, rasterOptions)
+			} else {
+				req, err = g.NewGeneratePointCloudExportRequest(context.Background(), pk, collects, options)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(req.Method, grid.SanitizeURL(req.URL))
+			return
+		}
+
+		var export *grid.GenerateExportObject
+		if raster {
+			export, _, err = g.GenerateRasterExport(pk, collects, rasterOptions)
+		} else {
+			export, _, err = g.GeneratePointCloudExport(pk, collects, options)
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -64,5 +133,46 @@ Export is used to initiate a GRiD export for the AOI and for each of the provide
 		fmt.Fprintln(w, "TASK ID\tEXPORT ID")
 		fmt.Fprintf(w, "%v\t%v\n", export.TaskID, export.ExportID)
 		w.Flush()
+
+		if !wait && !pull {
+			return
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if !waitForExport(ctx, export.TaskID) {
+			os.Exit(1)
+		}
+		if pull && !download(ctx, nil, export.ExportID) {
+			os.Exit(1)
+		}
 	},
 }
+
+// waitForExport waits for the export task to finish, printing each change in
+// its state, and reports whether it succeeded.
+func waitForExport(ctx context.Context, taskID string) bool {
+	updates := make(chan *grid.TaskObject)
+	done := make(chan struct{})
+	go func() {
+		for task := range updates {
+			fmt.Printf("Task %v is %v\n", task.TaskID, task.State)
+		}
+		close(done)
+	}()
+
+	_, err := g.WaitForTask(ctx, taskID, &grid.WaitOptions{Updates: updates})
+	<-done
+	var taskErr *grid.TaskError
+	switch {
+	case errors.As(err, &taskErr):
+		fmt.Println(taskErr)
+		if taskErr.Traceback != "" {
+			fmt.Println(taskErr.Traceback)
+		}
+		return false
+	case err != nil:
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
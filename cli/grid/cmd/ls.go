@@ -15,6 +15,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -83,7 +84,7 @@ AOIs.`,
 
 		// If the user has provided one or more arguments, assume they are primary
 		// keys and concurrently query the AOI and export API endpoints for details.
-		// var results []interface{}
+		// A key is expected to be found by at most one of them.
 		for _, arg := range args {
 			pk, err := strconv.Atoi(arg)
 			if err != nil {
@@ -91,77 +92,97 @@ AOIs.`,
 				continue
 			}
 
-			c1 := make(chan *grid.AOIDetail)
-			c2 := make(chan *grid.ExportDetail)
-			// unsure about how to handle errors anymore, as we expect users to be able to supply any type of pk, but GRiD now returns an error if you provide an export pk to the aoi endpoint and vice versa (plus product pks)
+			type aoiResult struct {
+				aoi *grid.AOIDetail
+				err error
+			}
+			type exportResult struct {
+				export *grid.ExportDetail
+				err    error
+			}
+			c1 := make(chan aoiResult, 1)
+			c2 := make(chan exportResult, 1)
 			go func() {
 				// get information on the AOI specified by the given primary key
-				a, _, _ := g.GetAOI(pk)
-				// if err != nil {
-				// 	log.Fatal(err.Error())
-				// }
-
-				c1 <- a
+				a, _, err := g.GetAOI(pk)
+				c1 <- aoiResult{a, err}
 			}()
 			go func() {
 				// get information on the export specified by the given primary key
-				a, _, _ := g.GetExport(pk)
-				// if err != nil {
-				// 	log.Fatal(err.Error())
-				// }
-
-				c2 <- a
+				a, _, err := g.GetExport(pk)
+				c2 <- exportResult{a, err}
 			}()
+			a, b := <-c1, <-c2
 
-			for i := 0; i < 2; i++ {
-				select {
-				case a := <-c1:
-					fmt.Println()
-					fmt.Println("NAME:", a.Name)
-					fmt.Println("CREATED AT:", a.CreatedAt)
-					fmt.Println("\nRASTER COLLECTS")
-					if len(a.RasterIntersects) > 0 {
-						w := new(tabwriter.Writer)
-						w.Init(os.Stdout, 0, 8, 3, '\t', 0)
-						fmt.Fprintln(w, "PRIMARY KEY\tNAME\tDATATYPE")
-						for _, vv := range a.RasterIntersects {
-							fmt.Fprintf(w, "%v\t%v\t%v\n", vv.Pk, vv.Name, vv.Datatype)
-						}
-						w.Flush()
-					}
-					fmt.Println("\nPOINTCLOUD COLLECTS")
-					if len(a.PointcloudIntersects) > 0 {
-						w := new(tabwriter.Writer)
-						w.Init(os.Stdout, 0, 8, 3, '\t', 0)
-						fmt.Fprintln(w, "PRIMARY KEY\tNAME\tDATATYPE")
-						for _, vv := range a.PointcloudIntersects {
-							fmt.Fprintf(w, "%v\t%v\t%v\n", vv.Pk, vv.Name, vv.Datatype)
-						}
-						w.Flush()
-					}
-					fmt.Println("\nEXPORTS")
-					if len(a.ExportSet) > 0 {
-						w := new(tabwriter.Writer)
-						w.Init(os.Stdout, 0, 8, 3, '\t', 0)
-						fmt.Fprintln(w, "PRIMARY KEY\tNAME\tDATATYPE\tSTARTED AT")
-						for _, vv := range a.ExportSet {
-							fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", vv.Pk, vv.Name, vv.Datatype, vv.StartedAt)
-						}
-						w.Flush()
-					}
-				case b := <-c2:
-					if len(b.ExportFiles) > 0 {
-						fmt.Println()
-						w := new(tabwriter.Writer)
-						w.Init(os.Stdout, 0, 8, 3, '\t', 0)
-						fmt.Fprintln(w, "PRIMARY KEY\tNAME")
-						for _, vv := range b.ExportFiles {
-							fmt.Fprintf(w, "%v\t%v\n", vv.Pk, vv.Name)
-						}
-						w.Flush()
-					}
-				}
+			notAOI := errors.Is(a.err, grid.ErrNotFound)
+			notExport := errors.Is(b.err, grid.ErrNotFound)
+			if notAOI && notExport {
+				fmt.Printf("\n%v is not an AOI or an export\n", pk)
+				continue
+			}
+
+			if a.err == nil {
+				printAOI(a.aoi)
+			} else if !notAOI {
+				fmt.Printf("\nError getting AOI %v: %v\n", pk, a.err)
+			}
+
+			if b.err == nil {
+				printExport(b.export)
+			} else if !notExport {
+				fmt.Printf("\nError getting export %v: %v\n", pk, b.err)
 			}
 		}
 	},
 }
+
+// printAOI prints the details of an AOI, including its collects and exports.
+func printAOI(a *grid.AOIDetail) {
+	fmt.Println()
+	fmt.Println("NAME:", a.Name)
+	fmt.Println("CREATED AT:", a.CreatedAt)
+	fmt.Println("\nRASTER COLLECTS")
+	if len(a.RasterIntersects) > 0 {
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 3, '\t', 0)
+		fmt.Fprintln(w, "PRIMARY KEY\tNAME\tDATATYPE")
+		for _, vv := range a.RasterIntersects {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", vv.Pk, vv.Name, vv.Datatype)
+		}
+		w.Flush()
+	}
+	fmt.Println("\nPOINTCLOUD COLLECTS")
+	if len(a.PointcloudIntersects) > 0 {
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 3, '\t', 0)
+		fmt.Fprintln(w, "PRIMARY KEY\tNAME\tDATATYPE")
+		for _, vv := range a.PointcloudIntersects {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", vv.Pk, vv.Name, vv.Datatype)
+		}
+		w.Flush()
+	}
+	fmt.Println("\nEXPORTS")
+	if len(a.ExportSet) > 0 {
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 3, '\t', 0)
+		fmt.Fprintln(w, "PRIMARY KEY\tNAME\tDATATYPE\tSTARTED AT")
+		for _, vv := range a.ExportSet {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", vv.Pk, vv.Name, vv.Datatype, vv.StartedAt)
+		}
+		w.Flush()
+	}
+}
+
+// printExport prints the files of an export.
+func printExport(b *grid.ExportDetail) {
+	if len(b.ExportFiles) > 0 {
+		fmt.Println()
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 3, '\t', 0)
+		fmt.Fprintln(w, "PRIMARY KEY\tNAME")
+		for _, vv := range b.ExportFiles {
+			fmt.Fprintf(w, "%v\t%v\n", vv.Pk, vv.Name)
+		}
+		w.Flush()
+	}
+}
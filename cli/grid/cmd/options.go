@@ -0,0 +1,204 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/venicegeo/grid-sdk-go"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	optionsFile string
+	preset      string
+	dryRun      bool
+
+	// flagOptions holds the values of the export option flags. The enum
+	// options are held as strings, then converted.
+	flagOptions                                  = grid.NewGeneratePointCloudExportOptions()
+	fileExportOptions, fileExportFormat, terrain string
+	rasterFormat, resampling                     string
+)
+
+// optionFlags maps each export option flag to a function that copies its value
+// into the options.
+var optionFlags = map[string]func(o *grid.GeneratePointCloudExportOptions){
+	"intensity":          func(o *grid.GeneratePointCloudExportOptions) { o.Intensity = flagOptions.Intensity },
+	"dim-classification": func(o *grid.GeneratePointCloudExportOptions) { o.DimClassification = flagOptions.DimClassification },
+	"hsrs":               func(o *grid.GeneratePointCloudExportOptions) { o.Hsrs = flagOptions.Hsrs },
+	"file-export-options": func(o *grid.GeneratePointCloudExportOptions) {
+		o.FileExportOptions = grid.FileExportOption(fileExportOptions)
+	},
+	"file-export-format": func(o *grid.GeneratePointCloudExportOptions) {
+		o.FileExportFormat = grid.FileExportFormat(fileExportFormat)
+	},
+	"compressed":        func(o *grid.GeneratePointCloudExportOptions) { o.Compressed = flagOptions.Compressed },
+	"send-email":        func(o *grid.GeneratePointCloudExportOptions) { o.SendEmail = flagOptions.SendEmail },
+	"generate-dem":      func(o *grid.GeneratePointCloudExportOptions) { o.GenerateDem = flagOptions.GenerateDem },
+	"cell-spacing":      func(o *grid.GeneratePointCloudExportOptions) { o.CellSpacing = flagOptions.CellSpacing },
+	"pcl-terrain":       func(o *grid.GeneratePointCloudExportOptions) { o.PclTerrain = grid.PclTerrain(terrain) },
+	"sri-hres":          func(o *grid.GeneratePointCloudExportOptions) { o.SriHResolution = flagOptions.SriHResolution },
+	"decimation-radius": func(o *grid.GeneratePointCloudExportOptions) { o.DecimationRadius = flagOptions.DecimationRadius },
+	"retile-size":       func(o *grid.GeneratePointCloudExportOptions) { o.RetileSize = flagOptions.RetileSize },
+	"retile-area":       func(o *grid.GeneratePointCloudExportOptions) { o.RetileArea = flagOptions.RetileArea },
+}
+
+// rasterOptionFlags maps each flag that applies to raster exports to a function
+// that copies its value into the options.
+var rasterOptionFlags = map[string]func(o *grid.GenerateRasterExportOptions){
+	"hsrs": func(o *grid.GenerateRasterExportOptions) { o.Hsrs = flagOptions.Hsrs },
+	"file-export-options": func(o *grid.GenerateRasterExportOptions) {
+		o.FileExportOptions = grid.FileExportOption(fileExportOptions)
+	},
+	"raster-format": func(o *grid.GenerateRasterExportOptions) { o.FileExportFormat = grid.RasterFormat(rasterFormat) },
+	"resampling":    func(o *grid.GenerateRasterExportOptions) { o.Resampling = grid.Resampling(resampling) },
+	"compressed":    func(o *grid.GenerateRasterExportOptions) { o.Compressed = flagOptions.Compressed },
+	"send-email":    func(o *grid.GenerateRasterExportOptions) { o.SendEmail = flagOptions.SendEmail },
+}
+
+func init() {
+	f := exportCmd.Flags()
+	f.StringVar(&preset, "preset", "", "start from the named export preset")
+	f.BoolVar(&dryRun, "dry-run", false, "print the export request instead of sending it")
+	addOptionFlags(f)
+
+	d := grid.NewGenerateRasterExportOptions()
+	f.StringVar(&rasterFormat, "raster-format", string(d.FileExportFormat), "raster file format, with --raster (GTiff, NITF, HFA or AAIGrid)")
+	f.StringVar(&resampling, "resampling", string(d.Resampling), "raster resampling method, with --raster (nearest, bilinear, cubic or average)")
+}
+
+// addOptionFlags adds the export option flags, and --options-file, to f.
+func addOptionFlags(f *pflag.FlagSet) {
+	f.StringVar(&optionsFile, "options-file", "", "read point cloud export options from a YAML or JSON file")
+
+	d := flagOptions
+	f.BoolVar(&d.Intensity, "intensity", d.Intensity, "include intensity")
+	f.BoolVar(&d.DimClassification, "dim-classification", d.DimClassification, "include classification")
+	f.StringVar(&d.Hsrs, "hsrs", d.Hsrs, "EPSG code of the horizontal spatial reference system")
+	f.StringVar(&fileExportOptions, "file-export-options", string(d.FileExportOptions), "export collects as individual files, or merged into one (individual or collect)")
+	f.StringVar(&fileExportFormat, "file-export-format", string(d.FileExportFormat), "file format (las12, las13 or las14)")
+	f.BoolVar(&d.Compressed, "compressed", d.Compressed, "compress the exported files")
+	f.BoolVar(&d.SendEmail, "send-email", d.SendEmail, "send an email when the export finishes")
+	f.BoolVar(&d.GenerateDem, "generate-dem", d.GenerateDem, "generate a DEM")
+	f.Float32Var(&d.CellSpacing, "cell-spacing", d.CellSpacing, "DEM cell spacing, with --generate-dem")
+	f.StringVar(&terrain, "pcl-terrain", string(d.PclTerrain), "terrain type for ground filtering (urban, mountainous, suburban or foliated)")
+	f.Float32Var(&d.SriHResolution, "sri-hres", d.SriHResolution, "horizontal resolution")
+	f.Float32Var(&d.DecimationRadius, "decimation-radius", d.DecimationRadius, "decimation radius")
+	f.Float32Var(&d.RetileSize, "retile-size", d.RetileSize, "retile size")
+	f.Float32Var(&d.RetileArea, "retile-area", d.RetileArea, "retile area")
+}
+
+/*
+exportOptions resolves the point cloud export options: the defaults, or the
+preset if given, overridden by any options file, overridden in turn by any
+option flags given. It returns nil if none of these is given, so the SDK's
+defaults are used.
+*/
+func exportOptions(cmd *cobra.Command) (*grid.GeneratePointCloudExportOptions, error) {
+	var changed []string
+	for name := range optionFlags {
+		if cmd.Flags().Changed(name) {
+			changed = append(changed, name)
+		}
+	}
+	for _, name := range []string{"raster-format", "resampling"} {
+		if cmd.Flags().Changed(name) {
+			return nil, fmt.Errorf("--%v applies only to --raster exports.", name)
+		}
+	}
+	if preset == "" && optionsFile == "" && len(changed) == 0 {
+		return nil, nil
+	}
+
+	options := grid.NewGeneratePointCloudExportOptions()
+	if preset != "" {
+		var err error
+		if options, err = loadPreset(preset); err != nil {
+			return nil, err
+		}
+	}
+	if optionsFile != "" {
+		if err := readOptionsFile(optionsFile, options); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(changed)
+	for _, name := range changed {
+		optionFlags[name](options)
+	}
+	return options, nil
+}
+
+/*
+rasterExportOptions resolves the raster export options: the defaults,
+overridden by the flags that apply to raster exports. It returns nil if none of
+these is given, so the SDK's defaults are used. Presets, options files and
+point cloud only flags are rejected.
+*/
+func rasterExportOptions(cmd *cobra.Command) (*grid.GenerateRasterExportOptions, error) {
+	if preset != "" || optionsFile != "" {
+		return nil, errors.New("--preset and --options-file apply only to point cloud exports, not --raster.")
+	}
+	var changed []string
+	for name := range optionFlags {
+		if _, ok := rasterOptionFlags[name]; !ok && cmd.Flags().Changed(name) {
+			return nil, fmt.Errorf("--%v applies only to point cloud exports, not --raster.", name)
+		}
+	}
+	for name := range rasterOptionFlags {
+		if cmd.Flags().Changed(name) {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	options := grid.NewGenerateRasterExportOptions()
+	for _, name := range changed {
+		rasterOptionFlags[name](options)
+	}
+	return options, nil
+}
+
+// readOptionsFile reads the options in the named JSON or YAML file into
+// options. Unknown options are rejected, so that misspellings are not ignored.
+func readOptionsFile(name string, options *grid.GeneratePointCloudExportOptions) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(options)
+	} else {
+		err = yaml.UnmarshalStrict(data, options)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %v: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/venicegeo/grid-sdk-go"
+)
+
+func init() {
+	addOptionFlags(presetSaveCmd.Flags())
+
+	presetCmd.AddCommand(presetLsCmd)
+	presetCmd.AddCommand(presetShowCmd)
+	presetCmd.AddCommand(presetSaveCmd)
+	presetCmd.AddCommand(presetRmCmd)
+}
+
+var presetCmd = &cobra.Command{
+	Use:   "preset",
+	Short: "Manage export presets",
+	Long: `
+Preset manages named sets of point cloud export options, saved in
+~/.grid/presets. Use a preset with 'grid export --preset <name>'; any other
+export options given override those of the preset.`,
+}
+
+var presetLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List export presets",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		names, err := grid.ListPresets()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+	},
+}
+
+var presetShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Print an export preset",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		options, err := loadPreset(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		b, err := json.MarshalIndent(options, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(b))
+	},
+}
+
+var presetSaveCmd = &cobra.Command{
+	Use:   "save [name] [options]",
+	Short: "Save an export preset",
+	Long: `
+Save the export options given, as flags or with --options-file as for
+'grid export', as the named preset, replacing any preset of that name.
+Options not given have their default values.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		options, err := exportOptions(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if options == nil {
+			options = grid.NewGeneratePointCloudExportOptions()
+		}
+		if err := grid.SavePreset(args[0], options); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var presetRmCmd = &cobra.Command{
+	Use:   "rm [name]...",
+	Short: "Delete export presets",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, name := range args {
+			if err := grid.DeletePreset(name); err != nil {
+				if os.IsNotExist(err) {
+					err = fmt.Errorf("No preset named %v.", name)
+				}
+				fmt.Println(err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// loadPreset loads the named preset, with a friendlier error if it does not
+// exist.
+func loadPreset(name string) (*grid.GeneratePointCloudExportOptions, error) {
+	options, err := grid.LoadPreset(name)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("No preset named %v. See 'grid preset ls'.", name)
+	}
+	return options, err
+}
@@ -0,0 +1,90 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// barWidth is the number of characters in the progress bar itself.
+const barWidth = 30
+
+// progressBar draws the total progress of a download on a terminal. If the
+// output is not a terminal, it draws nothing. The number of files is shown if
+// known.
+type progressBar struct {
+	w     io.Writer
+	files int
+	shown bool
+}
+
+func newProgressBar(f *os.File, files int) *progressBar {
+	if !isTerminal(f) {
+		return &progressBar{}
+	}
+	return &progressBar{w: f, files: files}
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// update redraws the bar with done of total bytes downloaded.
+func (b *progressBar) update(done, total int64) {
+	if b.w == nil {
+		return
+	}
+	filled := 0
+	if total > 0 {
+		filled = int(int64(barWidth) * done / total)
+		if filled > barWidth {
+			filled = barWidth
+		}
+	}
+	files := ""
+	if b.files > 0 {
+		files = fmt.Sprintf(" (%v files)", b.files)
+	}
+	fmt.Fprintf(b.w, "\r[%v%v] %v / %v%v",
+		strings.Repeat("=", filled), strings.Repeat(" ", barWidth-filled),
+		formatBytes(done), formatBytes(total), files)
+	b.shown = true
+}
+
+// finish clears the bar, so that other output may follow.
+func (b *progressBar) finish() {
+	if b.shown {
+		fmt.Fprintf(b.w, "\r%v\r", strings.Repeat(" ", barWidth+50))
+	}
+}
+
+// formatBytes formats n bytes in human-readable units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%v B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -15,19 +15,39 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/venicegeo/grid-sdk-go"
 )
 
+var (
+	watch         bool
+	watchInterval time.Duration
+)
+
+func init() {
+	taskCmd.Flags().BoolVarP(&watch, "watch", "w", false, "refresh the task states until every task is finished")
+	taskCmd.Flags().DurationVar(&watchInterval, "interval", grid.DefaultWaitOptions().Interval, "time before the first refresh with --watch, growing as for export --wait")
+}
+
 var taskCmd = &cobra.Command{
-	Use:   "task [Task ID]...",
+	Use:   "task [-w] [Task ID]...",
 	Short: "Get task details",
 	Long: `
-Lookup is used to retrieve the details of a GRiD task, including the status.`,
+Lookup is used to retrieve the details of a GRiD task, including the status.
+
+With --watch, the table is refreshed until every task is finished, and the exit
+status is non-zero if any task failed or was revoked.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := initClient()
 		if err != nil {
@@ -36,22 +56,106 @@ Lookup is used to retrieve the details of a GRiD task, including the status.`,
 		}
 
 		if len(args) == 0 {
-			fmt.Println("Please provide a WKT geometry")
+			fmt.Println("Please provide a task ID")
 			cmd.Usage()
 			return
 		}
 
-		for _, taskID := range args {
-			// get and print the suggested name for the current geometry
-			task, _, err := g.TaskDetails(taskID)
+		if watchInterval <= 0 {
+			fmt.Println("Please provide a positive --interval")
+			return
+		}
+
+		if !watch {
+			tasks, err := taskDetails(context.Background(), args)
 			if err != nil {
 				log.Fatal(err)
 			}
-			w := new(tabwriter.Writer)
-			w.Init(os.Stdout, 0, 8, 3, '\t', 0)
-			fmt.Fprintln(w, "ID\tNAME\tSTATE")
-			fmt.Fprintf(w, "%v\t%v\t%v\n", task.TaskID, task.Name, task.State)
-			w.Flush()
+			os.Stdout.Write(taskTable(tasks))
+			return
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		tasks, err := watchTasks(ctx, args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, task := range tasks {
+			if !task.State.IsSuccess() {
+				os.Exit(1)
+			}
 		}
 	},
 }
+
+// taskDetails gets the details of each of the given tasks.
+func taskDetails(ctx context.Context, ids []string) ([]*grid.TaskObject, error) {
+	tasks := make([]*grid.TaskObject, len(ids))
+	for i, id := range ids {
+		task, _, err := g.TaskDetailsWithContext(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		tasks[i] = task
+	}
+	return tasks, nil
+}
+
+// taskTable formats tasks as a table.
+func taskTable(tasks []*grid.TaskObject) []byte {
+	var buf bytes.Buffer
+	w := new(tabwriter.Writer)
+	w.Init(&buf, 0, 8, 3, '\t', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATE")
+	for _, task := range tasks {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", task.TaskID, task.Name, task.State)
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+/*
+watchTasks polls the given tasks until every one is finished, and returns them
+as last polled. The time between polls starts at watchInterval and grows as it
+does for WaitForTask. On a terminal, the table of tasks is redrawn in place
+after each poll; otherwise it is printed again whenever a task changes state.
+*/
+func watchTasks(ctx context.Context, ids []string) ([]*grid.TaskObject, error) {
+	tty := isTerminal(os.Stdout)
+	var last []byte
+	o := grid.DefaultWaitOptions()
+	interval := watchInterval
+	for {
+		tasks, err := taskDetails(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		table := taskTable(tasks)
+		switch {
+		case tty && last != nil:
+			// move up over the previous table and clear it
+			fmt.Printf("\033[%dA\033[J", strings.Count(string(last), "\n"))
+			os.Stdout.Write(table)
+		case tty || !bytes.Equal(table, last):
+			os.Stdout.Write(table)
+		}
+		last = table
+
+		finished := true
+		for _, task := range tasks {
+			finished = finished && task.State.IsTerminal()
+		}
+		if finished {
+			return tasks, nil
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		interval = max(watchInterval, min(time.Duration(float64(interval)*o.Multiplier), o.MaxInterval))
+	}
+}
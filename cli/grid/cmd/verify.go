@@ -0,0 +1,58 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/venicegeo/grid-sdk-go"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [dir]",
+	Short: "Verify downloaded files",
+	Long: `
+Verify the files downloaded into dir, the current directory by default, with
+'grid pull --sha256' against their recorded SHA-256 checksums.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		results, err := grid.VerifyManifest(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+				fmt.Printf("%v: FAILED (%v)\n", r.Name, r.Err)
+				continue
+			}
+			fmt.Printf("%v: OK\n", r.Name)
+		}
+		if failed > 0 {
+			fmt.Printf("%v of %v files failed verification\n", failed, len(results))
+			os.Exit(1)
+		}
+	},
+}
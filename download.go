@@ -0,0 +1,396 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileInfo describes a file downloaded from GRiD.
+type FileInfo struct {
+	Pk          int    // primary key of the file
+	Name        string // file name given by the server, if any
+	Size        int64  // number of bytes downloaded
+	ContentType string // media type given by the server
+	Path        string // where the file was saved, if saved to disk
+	Resumed     int64  // bytes kept from an earlier, partial download
+	SHA256      string // hex-encoded SHA-256 checksum, if computed
+}
+
+/*
+Download streams the file specified by the user-provided primary key into w,
+and reports the file name, size and content type given by the server. The file
+is checked against any size and checksums given by the server; if it does not
+match, an *IntegrityError is returned after it has been written to w.
+*/
+func (g *Grid) Download(ctx context.Context, pk int, w io.Writer) (*FileInfo, error) {
+	info, _, err := g.download(ctx, pk, w)
+	return info, err
+}
+
+/*
+DownloadToDir downloads the file specified by the user-provided primary key
+into dir, naming it as given by the server. The file is written to a partial
+file in dir, named for the primary key, and renamed into place once complete.
+
+If the download fails, the partial file is kept, and the next DownloadToDir of
+the same file into the same dir resumes where it left off, provided the server
+supports Range requests and the file has not changed.
+
+A lock file alongside the partial file is held for the whole download, so that
+a concurrent download of the same file into the same dir fails with
+ErrDownloadInProgress instead of clobbering it. A lock left behind by a process
+on this host that is no longer running is taken over.
+*/
+func (g *Grid) DownloadToDir(ctx context.Context, pk int, dir string) (*FileInfo, error) {
+	info, _, err := g.downloadToDir(ctx, pk, dir, nil)
+	return info, err
+}
+
+/*
+DownloadByPk downloads the file specified by the user-provided primary key into
+the current directory.
+
+Deprecated: Use DownloadToDir, which also reports where the file was saved.
+*/
+func (g *Grid) DownloadByPk(pk int) (*Response, error) {
+	return g.DownloadByPkWithContext(context.Background(), pk)
+}
+
+// DownloadByPkWithContext is like DownloadByPk, but the request is bound to
+// ctx.
+//
+// Deprecated: Use DownloadToDir.
+func (g *Grid) DownloadByPkWithContext(ctx context.Context, pk int) (*Response, error) {
+	_, resp, err := g.downloadToDir(ctx, pk, ".", nil)
+	return resp, err
+}
+
+func (g *Grid) download(ctx context.Context, pk int, w io.Writer) (*FileInfo, *Response, error) {
+	url := fmt.Sprintf("export/download/file/%v/", pk)
+
+	req, err := g.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := g.do(req)
+	if err != nil {
+		return nil, resp, err
+	}
+	defer resp.Body.Close()
+
+	info := fileInfo(pk, resp.Response)
+	check := newIntegrityCheck(info.Name, resp.Response, resp.ContentLength, true, g.manifest)
+	cw := &countingWriter{w: io.MultiWriter(w, check)}
+	if _, err := io.Copy(cw, resp.Body); err != nil {
+		return nil, resp, err
+	}
+	if err := check.verify(cw.n); err != nil {
+		return nil, resp, err
+	}
+
+	info.Size = cw.n
+	info.SHA256 = check.sha256()
+	return info, resp, nil
+}
+
+// ErrDownloadInProgress is returned by DownloadToDir when the same file is
+// already being downloaded into the same directory.
+var ErrDownloadInProgress = errors.New("download already in progress")
+
+// downloadToDir implements DownloadToDir. If progress is non-nil, it is called
+// with the bytes saved so far and the file size, or -1 if the size is unknown.
+func (g *Grid) downloadToDir(ctx context.Context, pk int, dir string, progress func(done, total int64)) (*FileInfo, *Response, error) {
+	part := filepath.Join(dir, fmt.Sprintf(".grid-%v.part", pk))
+
+	unlock, err := lockPart(part)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+
+	info, resp, err := g.downloadPart(ctx, pk, part, progress)
+	if errors.Is(err, errRangeNotSatisfiable) {
+		// the partial file is no use, so start again from scratch
+		removePart(part)
+		info, resp, err = g.downloadPart(ctx, pk, part, progress)
+	}
+	if err != nil {
+		return nil, resp, err
+	}
+
+	info.Path = filepath.Join(dir, info.Name)
+	if err := os.Rename(part, info.Path); err != nil {
+		return nil, resp, err
+	}
+	os.Remove(part + ".json")
+	if g.manifest {
+		if err := addToManifest(dir, info.Name, info.SHA256); err != nil {
+			return nil, resp, err
+		}
+	}
+	return info, resp, nil
+}
+
+/*
+downloadPart downloads the file specified by pk into the file at part. If part
+already holds the start of the file, from an earlier download that failed, it
+asks the server for just the remainder with a Range request. The If-Range
+header ensures that the server sends the whole file instead if it has changed
+since; the whole file is also accepted from servers that do not support
+ranges.
+
+The partial file is kept if the download fails, so that it may be resumed,
+unless it fails its integrity check.
+*/
+func (g *Grid) downloadPart(ctx context.Context, pk int, part string, progress func(done, total int64)) (*FileInfo, *Response, error) {
+	url := fmt.Sprintf("export/download/file/%v/", pk)
+
+	req, err := g.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	// offsets must count the bytes as stored, not as decoded by the transport
+	req.Header.Set("Accept-Encoding", "identity")
+
+	var offset int64
+	state := readPartState(part)
+	if fi, err := os.Stat(part); err == nil && fi.Size() > 0 {
+		offset = fi.Size()
+		req.Header.Set("Range", fmt.Sprintf("bytes=%v-", offset))
+		if state.validator() != "" {
+			req.Header.Set("If-Range", state.validator())
+		}
+	}
+
+	resp, err := g.do(req)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			return nil, resp, errRangeNotSatisfiable
+		}
+		return nil, resp, err
+	}
+	defer resp.Body.Close()
+
+	info := fileInfo(pk, resp.Response)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	size := resp.ContentLength
+	if resp.StatusCode == http.StatusPartialContent {
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return nil, resp, errRangeNotSatisfiable
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+		info.Resumed = offset
+		size = total
+	} else {
+		offset = 0
+	}
+
+	check := newIntegrityCheck(info.Name, resp.Response, size, offset == 0, g.manifest)
+	if offset > 0 && check.needed() {
+		// the checksums cover the whole file, including the part already saved
+		if err := hashFile(check, part, offset); err != nil {
+			return nil, resp, err
+		}
+	}
+
+	// record how to validate a later resume before writing anything
+	state = partState{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if err := state.write(part); err != nil {
+		return nil, resp, err
+	}
+
+	file, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return nil, resp, err
+	}
+	cw := &countingWriter{w: io.MultiWriter(file, check), n: offset}
+	if progress != nil {
+		cw.progress = func(n int64) { progress(n, size) }
+		progress(offset, size)
+	}
+	_, err = io.Copy(cw, resp.Body)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, resp, err
+	}
+	if err := check.verify(cw.n); err != nil {
+		// the file is corrupt, so resuming it would not help
+		removePart(part)
+		return nil, resp, err
+	}
+
+	info.Size = cw.n
+	info.SHA256 = check.sha256()
+	return info, resp, nil
+}
+
+// hashFile writes the first n bytes of the named file to w.
+func hashFile(w io.Writer, name string, n int64) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.CopyN(w, f, n)
+	return err
+}
+
+// errRangeNotSatisfiable reports that a partial download could not be resumed.
+var errRangeNotSatisfiable = errors.New("cannot resume partial download")
+
+// partState records the validators of a partial download, in a JSON file
+// alongside it.
+type partState struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// validator returns the value for the If-Range header. Weak ETags may not be
+// used.
+func (s partState) validator() string {
+	if s.ETag != "" && !strings.HasPrefix(s.ETag, "W/") {
+		return s.ETag
+	}
+	return s.LastModified
+}
+
+func readPartState(part string) partState {
+	var s partState
+	if data, err := ioutil.ReadFile(part + ".json"); err == nil {
+		json.Unmarshal(data, &s)
+	}
+	return s
+}
+
+func (s partState) write(part string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(part+".json", data, 0644)
+}
+
+/*
+lockPart takes the lock on the partial file at part, returning a function that
+releases it. The lock is a file created exclusively, so it is honored across
+processes, and holds the PID and host name of its owner. A lock whose owner
+was on this host but is no longer running, such as one killed before it could
+release it, is removed and taken.
+*/
+func lockPart(part string) (unlock func(), err error) {
+	lock := part + ".lock"
+	f, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) && staleLock(lock) {
+		os.Remove(lock)
+		f, err = os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	}
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("%w: %v exists (remove it if no download is running)", ErrDownloadInProgress, lock)
+	}
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	fmt.Fprintf(f, "%v %v\n", os.Getpid(), host)
+	f.Close()
+	return func() { os.Remove(lock) }, nil
+}
+
+// staleLock reports whether the lock file at lock was left by a process on this
+// host that is no longer running. Locks from other hosts, or that cannot be
+// read, are never stale.
+func staleLock(lock string) bool {
+	data, err := ioutil.ReadFile(lock)
+	if err != nil {
+		return false
+	}
+	var pid int
+	var host string
+	if _, err := fmt.Sscan(string(data), &pid, &host); err != nil {
+		return false
+	}
+	h, err := os.Hostname()
+	return err == nil && host == h && !processExists(pid)
+}
+
+func removePart(part string) {
+	os.Remove(part)
+	os.Remove(part + ".json")
+}
+
+// parseContentRange returns the first byte position and the complete length,
+// or -1 if unknown, of a Content-Range header such as "bytes 100-199/200".
+func parseContentRange(v string) (start, size int64, ok bool) {
+	var end int64
+	var length string
+	if _, err := fmt.Sscanf(v, "bytes %d-%d/%s", &start, &end, &length); err != nil {
+		return 0, 0, false
+	}
+	size, err := strconv.ParseInt(length, 10, 64)
+	if err != nil {
+		size = -1
+	}
+	return start, size, true
+}
+
+/*
+fileInfo describes the file in resp, taking its name from the
+Content-Disposition header. Any directory in the name is discarded, and a name
+based on pk is used if the server gave none.
+*/
+func fileInfo(pk int, resp *http.Response) *FileInfo {
+	info := &FileInfo{Pk: pk}
+	info.ContentType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	var name string
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	info.Name = safeName(name, fmt.Sprintf("grid-file-%v", pk))
+	return info
+}
+
+// countingWriter counts the bytes written through it, reporting the running
+// count to progress, if non-nil, after each write.
+type countingWriter struct {
+	w        io.Writer
+	n        int64
+	progress func(n int64)
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	if c.progress != nil {
+		c.progress(c.n)
+	}
+	return n, err
+}
@@ -0,0 +1,280 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newDownloadServer returns a client for a test server that serves files
+// named after their primary keys, with the given Content-Disposition file
+// name format.
+func newDownloadServer(t *testing.T, nameFormat string) *Grid {
+	return newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var pk int
+		if _, err := fmt.Sscanf(r.URL.Path, "/export/download/file/%d/", &pk); err != nil || pk == 404 {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		if nameFormat != "" {
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\""+nameFormat+"\"", pk))
+		}
+		w.Write(bytes.Repeat([]byte{byte(pk)}, 1000+pk))
+	}))
+}
+
+func TestDownload(t *testing.T) {
+	g := newDownloadServer(t, "file-%d.zip")
+
+	var buf bytes.Buffer
+	info, err := g.Download(context.Background(), 7, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "file-7.zip" || info.Size != 1007 || info.ContentType != "application/zip" {
+		t.Errorf("Unexpected file info %+v", info)
+	}
+	if buf.Len() != 1007 {
+		t.Errorf("Expected 1007 bytes, got %v", buf.Len())
+	}
+}
+
+func TestDownloadToDirConcurrent(t *testing.T) {
+	g := newDownloadServer(t, "file-%d.zip")
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for pk := 1; pk <= 5; pk++ {
+		wg.Add(1)
+		go func(pk int) {
+			defer wg.Done()
+			info, err := g.DownloadToDir(context.Background(), pk, dir)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if want := filepath.Join(dir, fmt.Sprintf("file-%d.zip", pk)); info.Path != want {
+				t.Errorf("Expected %v, got %v", want, info.Path)
+			}
+		}(pk)
+	}
+	wg.Wait()
+
+	for pk := 1; pk <= 5; pk++ {
+		data, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("file-%d.zip", pk)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, bytes.Repeat([]byte{byte(pk)}, 1000+pk)) {
+			t.Errorf("File %v has the wrong contents", pk)
+		}
+	}
+}
+
+func TestDownloadToDirInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	g := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="file.zip"`)
+		w.Write([]byte("first half, "))
+		w.(http.Flusher).Flush()
+		once.Do(func() { close(started) })
+		<-release
+		w.Write([]byte("second half"))
+	}))
+	dir := t.TempDir()
+
+	errs := make(chan error)
+	go func() {
+		_, err := g.DownloadToDir(context.Background(), 5, dir)
+		errs <- err
+	}()
+	<-started
+	if _, err := g.DownloadToDir(context.Background(), 5, dir); !errors.Is(err, ErrDownloadInProgress) {
+		t.Errorf("Expected %v, got %v", ErrDownloadInProgress, err)
+	}
+	close(release)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "file.zip"))
+	if err != nil || string(data) != "first half, second half" {
+		t.Errorf("Unexpected contents %q, %v", data, err)
+	}
+	if _, err := g.DownloadToDir(context.Background(), 5, dir); err != nil {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	}
+}
+
+func TestDownloadToDirStaleLock(t *testing.T) {
+	g := newDownloadServer(t, "file-%d.zip")
+	dir := t.TempDir()
+	lock := filepath.Join(dir, ".grid-5.part.lock")
+	host, _ := os.Hostname()
+
+	// a lock held by a running process is honored
+	ioutil.WriteFile(lock, []byte(fmt.Sprintf("%v %v\n", os.Getpid(), host)), 0644)
+	if _, err := g.DownloadToDir(context.Background(), 5, dir); !errors.Is(err, ErrDownloadInProgress) {
+		t.Errorf("Expected %v, got %v", ErrDownloadInProgress, err)
+	}
+
+	// one left by a process that has exited is taken over
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(lock, []byte(fmt.Sprintf("%v %v\n", cmd.Process.Pid, host)), 0644)
+	if _, err := g.DownloadToDir(context.Background(), 5, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(lock); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	}
+}
+
+func TestDownloadToDirFileNames(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"../../etc/passwd-%d", "passwd-3"},
+		{"", "grid-file-3"},
+	}
+	for _, tt := range tests {
+		g := newDownloadServer(t, tt.format)
+		dir := t.TempDir()
+		info, err := g.DownloadToDir(context.Background(), 3, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Path != filepath.Join(dir, tt.want) {
+			t.Errorf("Expected %v, got %v", filepath.Join(dir, tt.want), info.Path)
+		}
+	}
+}
+
+func TestDownloadToDirNotFound(t *testing.T) {
+	g := newDownloadServer(t, "file-%d.zip")
+	dir := t.TempDir()
+
+	_, err := g.DownloadToDir(context.Background(), 404, dir)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v, got %v", ErrNotFound, err)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), ".grid-") {
+			t.Errorf("Partial file %v was left behind", f.Name())
+		}
+	}
+}
+
+// newResumeServer returns a client for a test server that serves content for
+// every primary key with http.ServeContent, which supports Range and If-Range
+// requests, along with the Range header of the last request.
+func newResumeServer(t *testing.T, content []byte, etag string) (*Grid, *string) {
+	var rangeHeader string
+	modified := time.Date(2016, 6, 1, 0, 0, 0, 0, time.UTC)
+	g := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader = r.Header.Get("Range")
+		w.Header().Set("Content-Disposition", `attachment; filename="file.zip"`)
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		http.ServeContent(w, r, "file.zip", modified, bytes.NewReader(content))
+	}))
+	return g, &rangeHeader
+}
+
+func TestDownloadToDirResume(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	tests := []struct {
+		name    string
+		part    string
+		state   string
+		etag    string
+		resumed int64
+	}{
+		{"matching etag", "0123456789", `{"etag":"\"v1\""}`, `"v1"`, 10},
+		{"changed etag", "abcdefghij", `{"etag":"\"v0\""}`, `"v1"`, 0},
+		{"no validator", "0123456789", "", "", 10},
+		{"part too long", string(content) + "x", "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, rangeHeader := newResumeServer(t, content, tt.etag)
+			dir := t.TempDir()
+			part := filepath.Join(dir, ".grid-3.part")
+			ioutil.WriteFile(part, []byte(tt.part), 0644)
+			if tt.state != "" {
+				ioutil.WriteFile(part+".json", []byte(tt.state), 0644)
+			}
+
+			info, err := g.DownloadToDir(context.Background(), 3, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Resumed != tt.resumed || info.Size != int64(len(content)) {
+				t.Errorf("Unexpected file info %+v", info)
+			}
+			if tt.resumed > 0 && *rangeHeader != fmt.Sprintf("bytes=%d-", tt.resumed) {
+				t.Errorf("Unexpected Range header %q", *rangeHeader)
+			}
+
+			data, _ := ioutil.ReadFile(info.Path)
+			if !bytes.Equal(data, content) {
+				t.Error("File has the wrong contents")
+			}
+			files, _ := ioutil.ReadDir(dir)
+			if len(files) != 1 {
+				t.Errorf("Expected only the downloaded file, got %v files", len(files))
+			}
+		})
+	}
+}
+
+func TestDownloadToDirIgnoredRange(t *testing.T) {
+	// the first server ignores Range requests
+	g := newDownloadServer(t, "file-%d.zip")
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, ".grid-3.part"), []byte("stale"), 0644)
+
+	info, err := g.DownloadToDir(context.Background(), 3, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Resumed != 0 || info.Size != 1003 {
+		t.Errorf("Unexpected file info %+v", info)
+	}
+	data, _ := ioutil.ReadFile(info.Path)
+	if !bytes.Equal(data, bytes.Repeat([]byte{3}, 1003)) {
+		t.Error("File has the wrong contents")
+	}
+}
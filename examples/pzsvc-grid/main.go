@@ -16,6 +16,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -48,15 +49,11 @@ func processError(err error, writer http.ResponseWriter) {
 	if err == nil {
 		return
 	}
-	var status int
-	var message string
-	switch err.(type) {
-	// case grid.HTTPError:
-	// 	message = err.(grid.HTTPError).Message
-	// 	status = err.(grid.HTTPError).Status
-	default:
-		message = err.Error()
-		status = http.StatusBadRequest
+	message := err.Error()
+	status := http.StatusBadRequest
+	var errResp *grid.ErrorResponse
+	if errors.As(err, &errResp) {
+		status = errResp.StatusCode
 	}
 	http.Error(writer, message, status)
 }
@@ -0,0 +1,162 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExportDownload is the outcome of downloading every file of an export.
+type ExportDownload struct {
+	Export  *ExportDetail    // the export, as returned by GetExport
+	Dir     string           // directory the files were saved in
+	Results []DownloadResult // a result for each of the export's files
+}
+
+/*
+DownloadExport downloads every file of the export specified by the
+user-provided primary key into a directory in dir named for the export,
+creating it if necessary. It returns a *DownloadError if any download failed,
+along with the results of the others. To download the files in parallel, or to
+unzip them, use a DownloadManager.
+*/
+func (g *Grid) DownloadExport(ctx context.Context, exportPk int, dir string) (*ExportDownload, error) {
+	m := &DownloadManager{Grid: g, Dir: dir, Workers: 1}
+	return m.DownloadExport(ctx, exportPk)
+}
+
+/*
+DownloadExport downloads every file of the export specified by the
+user-provided primary key into a directory in m.Dir named for the export,
+creating it if necessary. It returns a *DownloadError if any download failed,
+along with the results of the others.
+*/
+func (m *DownloadManager) DownloadExport(ctx context.Context, exportPk int) (*ExportDownload, error) {
+	export, _, err := m.Grid.GetExportWithContext(ctx, exportPk)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := m.Dir
+	if parent == "" {
+		parent = "."
+	}
+	dl := &ExportDownload{
+		Export: export,
+		Dir:    filepath.Join(parent, safeName(export.Name, fmt.Sprintf("export-%v", exportPk))),
+	}
+	if err := os.MkdirAll(dl.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	pks := make([]int, len(export.ExportFiles))
+	for i, f := range export.ExportFiles {
+		pks[i] = f.Pk
+	}
+	em := *m
+	em.Dir = dl.Dir
+	dl.Results, err = em.Download(ctx, pks)
+	return dl, err
+}
+
+// safeName returns name with any directory discarded, so it may be used as a
+// file name, or fallback if nothing is left.
+func safeName(name, fallback string) string {
+	name = filepath.Base(filepath.Clean("/" + filepath.FromSlash(name)))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		return fallback
+	}
+	return name
+}
+
+// isZip reports whether a downloaded file is a zip file.
+func isZip(info *FileInfo) bool {
+	return info.ContentType == "application/zip" || info.ContentType == "application/x-zip-compressed" ||
+		strings.EqualFold(filepath.Ext(info.Name), ".zip")
+}
+
+// unzipDir returns the directory to unpack the zip file at path into: path
+// without its extension, or with "-unzipped" appended if it has none, so that
+// the directory never collides with the file itself.
+func unzipDir(path string) string {
+	if ext := filepath.Ext(path); ext != "" {
+		return strings.TrimSuffix(path, ext)
+	}
+	return path + "-unzipped"
+}
+
+/*
+unzip unpacks the zip file at path into dir, creating it if necessary. Entries
+that would be written outside dir, and any that are not regular files or
+directories, are rejected.
+*/
+func unzip(path, dir string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, f := range r.File {
+		name := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if !strings.HasPrefix(name, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("%v: invalid file name %q", path, f.Name)
+		}
+
+		switch mode := f.Mode(); {
+		case mode.IsDir():
+			if err := os.MkdirAll(name, 0755); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			if err := unzipFile(f, name); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%v: %q is not a regular file", path, f.Name)
+		}
+	}
+	return nil
+}
+
+func unzipFile(f *zip.File, name string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
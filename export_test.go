@@ -0,0 +1,142 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// zipFile returns a zip file holding the named files, each containing its own
+// name.
+func zipFile(t *testing.T, names ...string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(name))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newExportServer returns a client for a test server with an export, of
+// primary key 5, of two zip files.
+func newExportServer(t *testing.T) *Grid {
+	return newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2/export/5" {
+			w.Write([]byte(`{"pk": 5, "name": "../Great Sand Sea", "exportfiles": [
+				{"pk": 11, "name": "a.zip"}, {"pk": 12, "name": "b.zip"}]}`))
+			return
+		}
+		var pk int
+		if _, err := fmt.Sscanf(r.URL.Path, "/export/download/file/%d/", &pk); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="file-%d.zip"`, pk))
+		w.Write(zipFile(t, fmt.Sprintf("points-%d.las", pk), "meta/readme.txt"))
+	}))
+}
+
+func TestDownloadExport(t *testing.T) {
+	g := newExportServer(t)
+	dir := t.TempDir()
+
+	dl, err := g.DownloadExport(context.Background(), 5, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dl.Dir != filepath.Join(dir, "Great Sand Sea") || len(dl.Results) != 2 {
+		t.Fatalf("Unexpected download %+v", dl)
+	}
+	for _, pk := range []int{11, 12} {
+		if _, err := os.Stat(filepath.Join(dl.Dir, fmt.Sprintf("file-%d.zip", pk))); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if _, err := g.DownloadExport(context.Background(), 6, dir); err == nil {
+		t.Error("Should have received error")
+	}
+}
+
+func TestDownloadExportUnzip(t *testing.T) {
+	g := newExportServer(t)
+	m := &DownloadManager{Grid: g, Dir: t.TempDir(), Unzip: true}
+
+	dl, err := m.DownloadExport(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range dl.Results {
+		want := filepath.Join(dl.Dir, fmt.Sprintf("file-%d", r.Pk))
+		if r.Unzipped != want {
+			t.Errorf("Expected %v to be unzipped into %v, got %q", r.Pk, want, r.Unzipped)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(want, "meta", "readme.txt"))
+		if err != nil || string(data) != "meta/readme.txt" {
+			t.Errorf("Unexpected unzipped file %q, %v", data, err)
+		}
+	}
+}
+
+func TestDownloadManagerUnzipNoExtension(t *testing.T) {
+	g := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="points"`)
+		w.Write(zipFile(t, "points.las"))
+	}))
+	dir := t.TempDir()
+	m := &DownloadManager{Grid: g, Dir: dir, Unzip: true}
+
+	results, err := m.Download(context.Background(), []int{11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "points-unzipped"); results[0].Unzipped != want {
+		t.Errorf("Expected the file to be unzipped into %v, got %q", want, results[0].Unzipped)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "points-unzipped", "points.las"))
+	if err != nil || string(data) != "points.las" {
+		t.Errorf("Unexpected unzipped file %q, %v", data, err)
+	}
+}
+
+func TestUnzipInvalidName(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "evil.zip")
+	ioutil.WriteFile(path, zipFile(t, "../evil.txt"), 0644)
+
+	if err := unzip(path, filepath.Join(dir, "evil")); err == nil {
+		t.Error("Should have received error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.txt")); !os.IsNotExist(err) {
+		t.Error("File was written outside the directory")
+	}
+}
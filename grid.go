@@ -26,24 +26,28 @@ package grid
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 const (
+	libraryVersion = "0.2.3"
 	defaultBaseURL = "https://rsgis.erdc.dren.mil/te_ba/"
 	apiKey         = "CM69OHTGZJ2F08ET"
+	userAgent      = "grid-sdk-go/" + libraryVersion
 )
 
 // All the types
@@ -52,7 +56,7 @@ const (
 type AOIArray struct {
 	AOIList []struct {
 		Name      string `json:"name,omitempty"`
-		CreatedAt string `json:"created_at,omitempty"`
+		CreatedAt Time   `json:"created_at"`
 		IsActive  bool   `json:"is_active,omitempty"`
 		Source    string `json:"source,omitempty"`
 		User      int    `json:"user,omitempty"`
@@ -66,7 +70,7 @@ type AOIArray struct {
 // endpoint.
 type AOIDetail struct {
 	Name                 string                    `json:"name,omitempty"`
-	CreatedAt            string                    `json:"created_at,omitempty"`
+	CreatedAt            Time                      `json:"created_at"`
 	IsActive             bool                      `json:"is_active,omitempty"`
 	Source               string                    `json:"source,omitempty"`
 	User                 int                       `json:"user,omitempty"`
@@ -82,59 +86,67 @@ type AOIDetail struct {
 type Config struct {
 	Auth string `json:"auth"`
 	URL  string `json:"url"`
-}
 
-/*
-An Error reports more details on an individual error in an ErrorResponse.
-These are the possible validation error codes:
-    missing:
-        resource does not exist
-    missing_field:
-        a required field on a resource has not been set
-    invalid:
-        the formatting of a field is invalid
-    already_exists:
-        another resource has the same valid as this field
-GitHub API docs: http://developer.github.com/v3/#client-errors
-*/
-type Error struct {
-	Resource string `json:"resource"` // resource on which the error occurred
-	Field    string `json:"field"`    // field on which the error occurred
-	Code     string `json:"code"`     // validation error code
+	// CAFile is the path to a PEM bundle of additional trusted CAs.
+	CAFile string `json:"ca_file,omitempty"`
+	// MinTLSVersion is the minimum TLS version, such as "1.2".
+	MinTLSVersion string `json:"min_tls_version,omitempty"`
+	// Insecure disables TLS certificate verification. Never use in production.
+	Insecure bool `json:"insecure,omitempty"`
+
+	// AuthMethod selects how requests are authenticated: "basic" (the
+	// default, using Auth), "pki", "pkcs12" or "token".
+	AuthMethod string `json:"auth_method,omitempty"`
+	// CertFile is the client certificate, PEM-encoded for "pki" or a PKCS#12
+	// bundle for "pkcs12".
+	CertFile string `json:"cert_file,omitempty"`
+	// KeyFile is the PEM-encoded private key for "pki".
+	KeyFile string `json:"key_file,omitempty"`
+	// CertPassword is the password of the PKCS#12 bundle.
+	CertPassword string `json:"cert_password,omitempty"`
+	// Token is the bearer token for "token".
+	Token string `json:"token,omitempty"`
 }
 
 /*
-An ErrorResponse reports one or more errors caused by an API request.
-GitHub API docs: http://developer.github.com/v3/#client-errors
+An ErrorResponse reports an error returned by the GRiD API, that is, a
+response with a status code outside the 200 range.
+
+Use errors.Is to test for the class of error (ErrNotFound, ErrUnauthorized,
+ErrForbidden, ErrRateLimited, ErrServer or ErrValidation), or errors.As to
+inspect the details.
 */
 type ErrorResponse struct {
-	Response *http.Response // HTTP response that caused this error
-	Message  string         `json:"message"` // error message
-	Errors   []Error        `json:"errors"`  // more detail on individual errors
+	Response   *http.Response // HTTP response that caused this error
+	StatusCode int            // HTTP status code of the response
+	Method     string         // method of the request, if known
+	URL        string         // request URL, with credentials removed
+	Body       []byte         // raw response body
+	Message    string         // error message reported by GRiD, if any
 }
 
 // Export represents the export object that is returned as part of an AOIDetail.
 type Export struct {
-	Status    string `json:"status,omitempty"`
-	Name      string `json:"name,omitempty"`
-	Datatype  string `json:"datatype,omitempty"`
-	HSRS      string `json:"hsrs,omitempty"`
-	URL       string `json:"url,omitempty"`
-	Pk        int    `json:"pk,omitempty"`
-	StartedAt string `json:"started_at,omitempty"`
-	User      int    `json:"user,omitempty"`
+	Status    TaskState `json:"status,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Datatype  string    `json:"datatype,omitempty"`
+	HSRS      string    `json:"hsrs,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Pk        int       `json:"pk,omitempty"`
+	StartedAt Time      `json:"started_at"`
+	User      int       `json:"user,omitempty"`
 }
 
 // ExportDetail represents the export object that is returned as part of an
 // AOIDetail.
 type ExportDetail struct {
-	Status            string       `json:"status,omitempty"`
+	Status            TaskState    `json:"status,omitempty"`
 	Name              string       `json:"name,omitempty"`
 	Datatype          string       `json:"datatype,omitempty"`
 	HSRS              string       `json:"hsrs,omitempty"`
 	URL               string       `json:"url,omitempty"`
 	Pk                int          `json:"pk,omitempty"`
-	StartedAt         string       `json:"started_at,omitempty"`
+	StartedAt         Time         `json:"started_at"`
 	User              int          `json:"user,omitempty"`
 	RGB               bool         `json:"rgb,omitempty"`
 	Intensity         bool         `json:"intensity,omitempty"`
@@ -167,22 +179,34 @@ type GenerateExportObject struct {
 }
 
 // GeneratePointCloudExportOptions represents the options for a Generate Point
-// Cloud Export Operation
+// Cloud Export Operation. It may be read from JSON or YAML, keyed by the names
+// of the GRiD parameters.
 type GeneratePointCloudExportOptions struct {
-	Intensity         bool
-	DimClassification bool
-	Hsrs              string //EPSG code
-	FileExportOptions string //individual or collect
-	FileExportFormat  string
+	Intensity         bool             `json:"intensity" yaml:"intensity"`
+	DimClassification bool             `json:"dim_classification" yaml:"dim_classification"`
+	Hsrs              string           `json:"hsrs,omitempty" yaml:"hsrs,omitempty"`                               //EPSG code
+	FileExportOptions FileExportOption `json:"file_export_options,omitempty" yaml:"file_export_options,omitempty"` //individual or collect
+	FileExportFormat  FileExportFormat `json:"file_export_format,omitempty" yaml:"file_export_format,omitempty"`
+	Compressed        bool             `json:"compressed" yaml:"compressed"`
+	SendEmail         bool             `json:"send_email" yaml:"send_email"`
+	GenerateDem       bool             `json:"generate_dem" yaml:"generate_dem"`
+	CellSpacing       float32          `json:"cell_spacing,omitempty" yaml:"cell_spacing,omitempty"`
+	PclTerrain        PclTerrain       `json:"pcl_terrain,omitempty" yaml:"pcl_terrain,omitempty"` // urban, mountainous, suburban, or foliated
+	SriHResolution    float32          `json:"sri_hres,omitempty" yaml:"sri_hres,omitempty"`       // Horizontal resolution
+	DecimationRadius  float32          `json:"decimation_radius,omitempty" yaml:"decimation_radius,omitempty"`
+	RetileSize        float32          `json:"retile_size,omitempty" yaml:"retile_size,omitempty"`
+	RetileArea        float32          `json:"retile_area,omitempty" yaml:"retile_area,omitempty"`
+}
+
+// GenerateRasterExportOptions represents the options for a Generate Raster
+// Export Operation
+type GenerateRasterExportOptions struct {
+	Hsrs              string           //EPSG code
+	FileExportOptions FileExportOption //individual or collect
+	FileExportFormat  RasterFormat     // output format: GTiff, NITF, HFA or AAIGrid
+	Resampling        Resampling       // nearest, bilinear, cubic or average
 	Compressed        bool
 	SendEmail         bool
-	GenerateDem       bool
-	CellSpacing       float32
-	PclTerrain        string  // urban, mountainous, suburban, or foliated
-	SriHResolution    float32 // Horizontal resolution
-	DecimationRadius  float32
-	RetileSize        float32
-	RetileArea        float32
 }
 
 // Geoname represents the geoname object that is returned by the geoname
@@ -194,12 +218,45 @@ type Geoname struct {
 
 // Grid defines the GRiD client.
 type Grid struct {
+	// Auth is the base64-encoded "username:password" used for Basic
+	// authentication when Authenticator is nil.
 	Auth string
+
+	// Authenticator adds credentials to each request, taking precedence over
+	// Auth.
+	Authenticator Authenticator
+
 	// Base URL for API requests.  Defaults to GRiD TE, but can be
 	// set to a domain endpoint to use with other instances.  BaseURL should
 	// always be specified with a trailing slash.
 	BaseURL   *url.URL
 	Transport http.RoundTripper
+
+	// HTTPClient is used to send requests. If nil, a client using Transport is
+	// created for each request.
+	HTTPClient *http.Client
+
+	// APIKey is sent as the source parameter of every request. Defaults to the
+	// SDK's own key when empty.
+	APIKey string
+
+	// UserAgent is sent as the User-Agent header, if not empty.
+	UserAgent string
+
+	// RetryPolicy controls the retrying of transient failures. If nil,
+	// requests are not retried.
+	RetryPolicy *RetryPolicy
+
+	timeout    time.Duration
+	limiter    *rateLimiter
+	inflight   chan struct{}
+	tlsOptions []func(*tls.Config) error
+	middleware []Middleware
+	manifest   bool
+
+	// formGET is set once the server has rejected a form POST, so that
+	// requests carrying geometries are sent as GET from then on.
+	formGET atomic.Bool
 }
 
 // PointcloudCollect represents the pointcloud collect object that is returned
@@ -216,7 +273,7 @@ type PointcloudDatasetSimple struct {
 	Name            string  `json:"name,omitempty"`
 	Pk              int     `json:"pk,omitempty"`
 	Sensor          string  `json:"sensor,omitempty"`
-	CollectedAt     string  `json:"collected_at,omitempty"`
+	CollectedAt     Time    `json:"collected_at"`
 	Classification  string  `json:"classification,omitempty"`
 	Area            float32 `json:"area,omitempty"`
 	Filesize        int     `json:"filesize,omitempty"`
@@ -239,7 +296,7 @@ type RasterDatasetSimple struct {
 	Name            string  `json:"name,omitempty"`
 	Pk              int     `json:"pk,omitempty"`
 	Sensor          string  `json:"sensor,omitempty"`
-	CollectedAt     string  `json:"collected_at,omitempty"`
+	CollectedAt     Time    `json:"collected_at"`
 	Classification  string  `json:"classification,omitempty"`
 	Area            float32 `json:"area,omitempty"`
 	Filesize        int     `json:"filesize,omitempty"`
@@ -253,58 +310,118 @@ links.
 */
 type Response struct {
 	*http.Response
+
+	// Attempts is the number of times the request was sent. A value greater
+	// than one means the request was retried.
+	Attempts int
 }
 
 // TaskObject represents the state of a GRiD task
 type TaskObject struct {
-	Traceback string `json:"task_traceback,omitempty"`
-	State     string `json:"task_state,omitempty"`
-	Timestamp string `json:"task_tstamp,omitempty"`
-	Name      string `json:"task_name,omitempty"`
-	TaskID    string `json:"task_id,omitempty"`
+	Traceback string    `json:"task_traceback,omitempty"`
+	State     TaskState `json:"task_state,omitempty"`
+	Timestamp Time      `json:"task_tstamp"`
+	Name      string    `json:"task_name,omitempty"`
+	TaskID    string    `json:"task_id,omitempty"`
 }
 
 // TDA ...
 type TDA struct {
-	CreatedAt string `json:"created_at,omitempty"`
-	Name      string `json:"name,omitempty"`
-	Notes     string `json:"notes,omitempty"`
-	Pk        int    `json:"pk,omitempty"`
-	Status    string `json:"status,omitempty"`
-	TDAType   string `json:"tda_type,omitempty"`
-	URL       string `json:"url,omitempty"`
+	CreatedAt Time      `json:"created_at"`
+	Name      string    `json:"name,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	Pk        int       `json:"pk,omitempty"`
+	Status    TaskState `json:"status,omitempty"`
+	TDAType   string    `json:"tda_type,omitempty"`
+	URL       string    `json:"url,omitempty"`
 }
 
 // TDASet represents the TDA set object that is returned by the export endpoint.
 type TDASet struct {
-	Status    string `json:"status,omitempty"`
-	TDAType   string `json:"tda_type,omitempty"`
-	Name      string `json:"name,omitempty"`
-	URL       string `json:"url,omitempty"`
-	CreatedAt string `json:"created_at,omitempty"`
-	Pk        int    `json:"pk,omitempty"`
-	Notes     string `json:"notes,omitempty"`
+	Status    TaskState `json:"status,omitempty"`
+	TDAType   string    `json:"tda_type,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	CreatedAt Time      `json:"created_at"`
+	Pk        int       `json:"pk,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
 }
 
+// Errors returned, wrapped in an *ErrorResponse, for the API errors that
+// callers most often need to act on. ErrValidation is also returned, wrapped
+// in an *OptionError, for invalid options found before a request is sent.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+	ErrValidation   = errors.New("validation failed")
+)
+
+// maxErrorMessage is the longest message taken from a non-JSON error body.
+const maxErrorMessage = 200
+
 /*
 CheckResponse checks the API response for errors, and returns them if present.
 A response is considered an error if it has a status code outside the 200 range.
 API error responses are expected to have either no response body, or a JSON
-response body that maps to ErrorResponse. Any other response body will be
-silently ignored.
+response body with a "detail", "error" or "message" field. Any other response
+body is kept in the ErrorResponse, and its first line used as the message.
 */
 func CheckResponse(r *http.Response) error {
 	if c := r.StatusCode; 200 <= c && c <= 299 {
 		return nil
 	}
-	errorResponse := &ErrorResponse{Response: r}
-	data, err := ioutil.ReadAll(r.Body)
-	if err == nil && data != nil {
-		json.Unmarshal(data, errorResponse)
+	errorResponse := &ErrorResponse{Response: r, StatusCode: r.StatusCode}
+	if r.Request != nil {
+		errorResponse.Method = r.Request.Method
+		if u := SanitizeURL(r.Request.URL); u != nil {
+			errorResponse.URL = u.String()
+		}
+	}
+	if r.Body != nil {
+		data, err := ioutil.ReadAll(r.Body)
+		if err == nil {
+			errorResponse.Body = data
+		}
 	}
+	errorResponse.Message = errorMessage(errorResponse.Body)
 	return errorResponse
 }
 
+// errorMessage extracts the error message from the body of an error response.
+func errorMessage(body []byte) string {
+	var v struct {
+		Detail  string `json:"detail"`
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &v); err == nil {
+		switch {
+		case v.Detail != "":
+			return v.Detail
+		case v.Error != "":
+			return v.Error
+		default:
+			return v.Message
+		}
+	}
+
+	// not JSON, so fall back to the first line of any plain text
+	text := strings.TrimSpace(string(body))
+	if strings.HasPrefix(text, "<") {
+		return ""
+	}
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = strings.TrimSpace(text[:i])
+	}
+	if len(text) > maxErrorMessage {
+		text = text[:maxErrorMessage] + "..."
+	}
+	return text
+}
+
 // newResponse creates a new Response for the provided http.Response.
 func newResponse(r *http.Response) *Response {
 	response := &Response{Response: r}
@@ -312,74 +429,176 @@ func newResponse(r *http.Response) *Response {
 }
 
 func (r *ErrorResponse) Error() string {
-	return fmt.Sprintf("%v %v: %d %v %+v",
-		r.Response.Request.Method, sanitizeURL(r.Response.Request.URL),
-		r.Response.StatusCode, r.Message, r.Errors)
+	msg := fmt.Sprintf("%d %v", r.StatusCode, http.StatusText(r.StatusCode))
+	if r.URL != "" {
+		msg = fmt.Sprintf("%v %v: %v", r.Method, r.URL, msg)
+	}
+	if r.Message != "" {
+		msg += ": " + r.Message
+	}
+	return msg
 }
 
-/*
-sanitizeURL was originally used (in the GitHub code) to redact the client_id
-and client_secret tokens from the URL which may be exposed to the user,
-specifically in the ErrorResponse error message.
+// Unwrap returns the error for the class of the response's status code, if
+// any, so that errors.Is(err, ErrNotFound) and the like work.
+func (r *ErrorResponse) Unwrap() error {
+	switch c := r.StatusCode; {
+	case c == http.StatusBadRequest, c == http.StatusUnprocessableEntity:
+		return ErrValidation
+	case c == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case c == http.StatusForbidden:
+		return ErrForbidden
+	case c == http.StatusNotFound:
+		return ErrNotFound
+	case c == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case c >= 500:
+		return ErrServer
+	}
+	return nil
+}
 
-We may not need it, but then again, maybe we will have our own sanitization.
+/*
+SanitizeURL returns a copy of uri that is safe to expose to the user, such as
+in an ErrorResponse error message, a log or a dry run, with any user info and
+the source API key redacted. It returns nil if uri is nil.
 */
-func sanitizeURL(uri *url.URL) *url.URL {
+func SanitizeURL(uri *url.URL) *url.URL {
 	if uri == nil {
 		return nil
 	}
-	return uri
+	u := *uri
+	u.User = nil
+	q := u.Query()
+	if q.Get("source") != "" {
+		q.Set("source", "REDACTED")
+		u.RawQuery = q.Encode()
+	}
+	return &u
 }
 
-// New returns a new GRiD API client.
-func New() (*Grid, error) {
+/*
+New returns a new GRiD API client configured from the config file. Any options
+are applied after those read from the config file, and so take precedence.
+*/
+func New(opts ...Option) (*Grid, error) {
 	config, err := GetConfig()
 	if err != nil {
 		return nil, err
 	}
-	if config.URL == "" {
-		config.URL = defaultBaseURL
+
+	authOpt, err := configAuthOption(config)
+	if err != nil {
+		return nil, err
+	}
+	cfgOpts := []Option{authOpt}
+	if config.URL != "" {
+		cfgOpts = append(cfgOpts, WithBaseURL(config.URL))
+	}
+	if config.CAFile != "" {
+		cfgOpts = append(cfgOpts, WithCAFile(config.CAFile))
+	}
+	if config.MinTLSVersion != "" {
+		version, err := parseTLSVersion(config.MinTLSVersion)
+		if err != nil {
+			return nil, err
+		}
+		cfgOpts = append(cfgOpts, WithMinTLSVersion(version))
+	}
+	if config.Insecure {
+		cfgOpts = append(cfgOpts, WithInsecureSkipVerify())
+	}
+	return NewClient(append(cfgOpts, opts...)...)
+}
+
+/*
+NewClient returns a new GRiD API client configured only by the given options,
+without reading the config file. Unless overridden, the client targets the
+default GRiD instance with the SDK's API key and User-Agent.
+*/
+func NewClient(opts ...Option) (*Grid, error) {
+	baseURL, _ := url.Parse(defaultBaseURL)
+	g := &Grid{
+		BaseURL:     baseURL,
+		APIKey:      apiKey,
+		UserAgent:   userAgent,
+		RetryPolicy: DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
+			return nil, err
+		}
+	}
+
+	client := &http.Client{}
+	if g.HTTPClient != nil {
+		c := *g.HTTPClient
+		client = &c
+	}
+	if g.Transport != nil {
+		client.Transport = g.Transport
+	}
+	if g.timeout != 0 {
+		client.Timeout = g.timeout
+	}
+	if err := g.applyTLS(client); err != nil {
+		return nil, err
 	}
-	parsedBaseURL, _ := url.Parse(config.URL)
-	return &Grid{
-		Auth:    config.Auth,
-		BaseURL: parsedBaseURL,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-	}, nil
+	g.HTTPClient = client
+
+	return g, nil
 }
 
 /*
-Lookup the suggested name for the given geometry.
+Lookup the suggested name for the given geometry. As for AddAOI, the geometry
+is sent in a form POST where the server accepts one.
 
 GRiD API docs:
 https://github.com/CRREL/GRiD-API/blob/master/composed_api.rst#lookup-geoname
 */
 func (g *Grid) Lookup(geom string) (*Geoname, *Response, error) {
+	return g.LookupWithContext(context.Background(), geom)
+}
+
+// LookupWithContext is like Lookup, but the request is bound to ctx.
+func (g *Grid) LookupWithContext(ctx context.Context, geom string) (*Geoname, *Response, error) {
 	if geom == "" {
 		return nil, nil, errors.New("Please provide a WKT geometry string")
 	}
 
 	v := url.Values{}
 	v.Set("geom", geom)
-	vals := v.Encode()
-	qurl := fmt.Sprintf("api/v2/geoname?%v", vals)
-
-	req, err := g.NewRequest("GET", qurl, nil)
 
 	name := new(Geoname)
-	resp, err := g.Do(req, name)
-	return name, resp, err
+	resp, err := g.doForm(readOnly(ctx), "api/v2/geoname", v, name)
+	if err != nil {
+		return nil, resp, err
+	}
+	return name, resp, nil
 }
 
 /*
 NewRequest creates an API request. A relative URL can be provided in urlStr, in
 which case it is resolved relative to the BaseURL of the Client. Relative URLs
 should always be specified without a preceding slash. If  specified, the value
-pointed to by body is JSON encoded and included as the request body.
+pointed to by body is JSON encoded and included as the request body, unless it
+is a url.Values, which is sent as an application/x-www-form-urlencoded form.
 */
 func (g *Grid) NewRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+	return g.NewRequestWithContext(context.Background(), method, urlStr, body)
+}
+
+/*
+NewRequestWithContext is like NewRequest, but the returned request carries ctx.
+Do honors the deadline and cancellation of that context for the lifetime of
+the request, including reading the response body.
+*/
+func (g *Grid) NewRequestWithContext(ctx context.Context, method, urlStr string, body interface{}) (*http.Request, error) {
+	if ctx == nil {
+		return nil, errors.New("context must be non-nil")
+	}
+
 	rel, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, err
@@ -388,23 +607,46 @@ func (g *Grid) NewRequest(method, urlStr string, body interface{}) (*http.Reques
 	u := g.BaseURL.ResolveReference(rel)
 
 	var buf io.ReadWriter
-	if body != nil {
+	var contentType string
+	switch body := body.(type) {
+	case nil:
+	case url.Values:
+		buf = bytes.NewBufferString(body.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	default:
 		buf = new(bytes.Buffer)
 		err := json.NewEncoder(buf).Encode(body)
 		if err != nil {
 			return nil, err
 		}
+		contentType = "application/json"
 	}
 
-	req, err := http.NewRequest(method, u.String(), buf)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), buf)
 	if err != nil {
 		return nil, err
 	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 
-	req.Header.Set("Authorization", "Basic "+g.Auth)
+	if g.Authenticator != nil {
+		if err := g.Authenticator.Authenticate(req); err != nil {
+			return nil, err
+		}
+	} else if g.Auth != "" {
+		req.Header.Set("Authorization", "Basic "+g.Auth)
+	}
+	if g.UserAgent != "" {
+		req.Header.Set("User-Agent", g.UserAgent)
+	}
 
+	key := g.APIKey
+	if key == "" {
+		key = apiKey
+	}
 	a := req.URL.Query()
-	a.Add("source", apiKey)
+	a.Add("source", key)
 	req.URL.RawQuery = a.Encode()
 
 	return req, nil
@@ -415,57 +657,121 @@ Do sends an API request and returns the API response.  The API response is JSON
 decoded and stored in the value pointed to by v, or returned as an error if an
 API error has occurred.  If v implements the io.Writer interface, the raw
 response body will be written to v, without attempting to first decode it.
+
+The request is bound to its own context (see NewRequestWithContext). If that
+context is canceled or its deadline expires, the context's error is returned.
+Transient failures are retried according to g.RetryPolicy, and each attempt
+passes through the client's middleware (see Use).
 */
 func (g *Grid) Do(req *http.Request, v interface{}) (*Response, error) {
-	client := &http.Client{
-		Transport: g.Transport,
+	response, err := g.do(req)
+	if err != nil {
+		return response, err
 	}
-	resp, err := client.Do(req)
+	defer response.Body.Close()
+
+	if v != nil {
+		if w, ok := v.(io.Writer); ok {
+			_, err = io.Copy(w, response.Body)
+		} else {
+			err = json.NewDecoder(response.Body).Decode(v)
+		}
+	}
+	return response, err
+}
+
+/*
+do is like Do, but leaves the body of a successful response unread for the
+caller, who is responsible for closing it.
+*/
+func (g *Grid) do(req *http.Request) (*Response, error) {
+	ctx := req.Context()
+
+	resp, attempts, err := g.send(req)
 	if err != nil {
+		// If we got an error, and the context has been canceled, the context's
+		// error is probably more useful.
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
 		return nil, err
 	}
 
-	defer resp.Body.Close()
-
 	response := newResponse(resp)
+	response.Attempts = attempts
 
 	err = CheckResponse(resp)
 	if err != nil {
+		resp.Body.Close()
 		// even though there was an error, we still return the response
 		// in case the caller wants to inspect it further
 		return response, err
 	}
+	return response, nil
+}
 
-	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
-		} else {
-			err = json.NewDecoder(resp.Body).Decode(v)
+/*
+doForm sends a request carrying a WKT geometry, which may be too long for a
+URL and should be kept out of proxy logs, as a POST of the form. Servers that
+predate form POSTs reject them with 405 Method Not Allowed or 501 Not
+Implemented, in which case the form is sent again as the query string of a GET,
+as are all such requests from then on. As POSTs, they are not retried unless
+ctx is marked readOnly or the RetryPolicy allows all methods.
+*/
+func (g *Grid) doForm(ctx context.Context, urlStr string, form url.Values, v interface{}) (*Response, error) {
+	if !g.formGET.Load() {
+		req, err := g.NewRequestWithContext(ctx, "POST", urlStr, form)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := g.Do(req, v)
+		if resp == nil || (resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented) {
+			return resp, err
 		}
+		g.formGET.Store(true)
 	}
-	return response, err
+
+	req, err := g.NewRequestWithContext(ctx, "GET", urlStr+"?"+form.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return g.Do(req, v)
 }
 
 /*
-ListAOIs retrieves all AOIs intersecting the optional geometry.
+ListAOIs retrieves all AOIs intersecting the optional geometry. As for Lookup,
+the geometry is sent in a form POST where the server accepts one.
 
 GRiD API docs:
 https://github.com/CRREL/GRiD-API/blob/master/composed_api.rst#get-a-users-aoi-list
 */
 func (g *Grid) ListAOIs(geom string) (*AOIArray, *Response, error) {
-	v := url.Values{}
+	return g.ListAOIsWithContext(context.Background(), geom)
+}
+
+// ListAOIsWithContext is like ListAOIs, but the request is bound to ctx.
+func (g *Grid) ListAOIsWithContext(ctx context.Context, geom string) (*AOIArray, *Response, error) {
+	aoiList := new(AOIArray)
+	var resp *Response
+	var err error
 	if geom != "" {
+		v := url.Values{}
 		v.Set("geom", geom)
+		resp, err = g.doForm(readOnly(ctx), "api/v2/aoi", v, aoiList)
+	} else {
+		var req *http.Request
+		req, err = g.NewRequestWithContext(ctx, "GET", "api/v2/aoi", nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err = g.Do(req, aoiList)
 	}
-	vals := v.Encode()
-	qurl := fmt.Sprintf("api/v2/aoi?%v", vals)
-
-	req, err := g.NewRequest("GET", qurl, nil)
-
-	aoiList := new(AOIArray)
-	resp, err := g.Do(req, aoiList)
-
-	return aoiList, resp, err
+	if err != nil {
+		return nil, resp, err
+	}
+	return aoiList, resp, nil
 }
 
 /*
@@ -476,26 +782,39 @@ GRiD API docs:
 https://github.com/CRREL/GRiD-API/blob/master/composed_api.rst#get-aoi-details
 */
 func (g *Grid) GetAOI(pk int) (*AOIDetail, *Response, error) {
+	return g.GetAOIWithContext(context.Background(), pk)
+}
+
+// GetAOIWithContext is like GetAOI, but the request is bound to ctx.
+func (g *Grid) GetAOIWithContext(ctx context.Context, pk int) (*AOIDetail, *Response, error) {
 	url := fmt.Sprintf("api/v2/aoi/%v", pk)
 
-	req, err := g.NewRequest("GET", url, nil)
+	req, err := g.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	aoiDetail := new(AOIDetail)
-	resp, _ := g.Do(req, aoiDetail)
-
+	resp, err := g.Do(req, aoiDetail)
+	if err != nil {
+		return nil, resp, err
+	}
 	return aoiDetail, resp, nil
 }
 
 /*
-AddAOI uploads the given geometry to create a new AOI.
+AddAOI uploads the given geometry to create a new AOI. The geometry is sent in
+a form POST, or as a GET query string to servers that do not accept one.
 
 GRiD API docs:
 https://github.com/CRREL/GRiD-API/blob/master/composed_api.rst#add-aoi
 */
 func (g *Grid) AddAOI(name, geom string, subscribe bool) (*AOIDetail, *Response, error) {
+	return g.AddAOIWithContext(context.Background(), name, geom, subscribe)
+}
+
+// AddAOIWithContext is like AddAOI, but the request is bound to ctx.
+func (g *Grid) AddAOIWithContext(ctx context.Context, name, geom string, subscribe bool) (*AOIDetail, *Response, error) {
 	if name == "" {
 		return nil, nil, errors.New("Please provide an AOI name and WKT geometry string")
 	}
@@ -510,13 +829,13 @@ func (g *Grid) AddAOI(name, geom string, subscribe bool) (*AOIDetail, *Response,
 	if subscribe {
 		v.Add("subscribe", "True")
 	}
-	vals := v.Encode()
-	qurl := fmt.Sprintf("api/v2/aoi/add?%v", vals)
 
-	req, err := g.NewRequest("GET", qurl, nil)
 	addAOIResponse := new(AOIDetail)
-	resp, err := g.Do(req, addAOIResponse)
-	return addAOIResponse, resp, err
+	resp, err := g.doForm(ctx, "api/v2/aoi/add", v, addAOIResponse)
+	if err != nil {
+		return nil, resp, err
+	}
+	return addAOIResponse, resp, nil
 }
 
 /*
@@ -527,39 +846,24 @@ GRiD API docs:
 https://github.com/CRREL/GRiD-API/blob/master/composed_api.rst#get-export-details
 */
 func (g *Grid) GetExport(pk int) (*ExportDetail, *Response, error) {
-	qurl := fmt.Sprintf("api/v2/export/%v", pk)
-
-	req, err := g.NewRequest("GET", qurl, nil)
-
-	exportDetail := new(ExportDetail)
-	resp, err := g.Do(req, exportDetail)
-	return exportDetail, resp, err
+	return g.GetExportWithContext(context.Background(), pk)
 }
 
-// DownloadByPk downloads the file specified by the user-provided primary key.
-func (g *Grid) DownloadByPk(pk int) (*Response, error) {
-	url := fmt.Sprintf("export/download/file/%v/", pk)
-
-	req, err := g.NewRequest("GET", url, nil)
+// GetExportWithContext is like GetExport, but the request is bound to ctx.
+func (g *Grid) GetExportWithContext(ctx context.Context, pk int) (*ExportDetail, *Response, error) {
+	qurl := fmt.Sprintf("api/v2/export/%v", pk)
 
-	file, err := os.Create("temp")
+	req, err := g.NewRequestWithContext(ctx, "GET", qurl, nil)
 	if err != nil {
-		panic(err)
-	}
-	defer file.Close()
-	resp, err := g.Do(req, file)
-	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
 
-	cd := resp.Header.Get("Content-Disposition")
-	_, params, err := mime.ParseMediaType(cd)
+	exportDetail := new(ExportDetail)
+	resp, err := g.Do(req, exportDetail)
 	if err != nil {
-		return nil, err
+		return nil, resp, err
 	}
-	err = os.Rename(file.Name(), params["filename"])
-	return resp, err
+	return exportDetail, resp, nil
 }
 
 /*
@@ -570,8 +874,8 @@ func NewGeneratePointCloudExportOptions() *GeneratePointCloudExportOptions {
 	return &GeneratePointCloudExportOptions{
 		Intensity:         true,
 		DimClassification: true,
-		FileExportOptions: "individual",
-		FileExportFormat:  "las12",
+		FileExportOptions: ExportIndividual,
+		FileExportFormat:  FormatLAS12,
 		Compressed:        true,
 		SendEmail:         false,
 		GenerateDem:       false,
@@ -580,17 +884,50 @@ func NewGeneratePointCloudExportOptions() *GeneratePointCloudExportOptions {
 }
 
 /*
-GeneratePointCloudExport does just that for the given PK and set of products
+GeneratePointCloudExport does just that for the given PK and set of products.
+The options are checked with Validate before the request is sent, and nil
+options are replaced by the defaults.
 
 GRiD API docs:
 https://github.com/CRREL/GRiD-API/blob/master/composed_api.rst#generate-point-cloud-export
 */
 func (g *Grid) GeneratePointCloudExport(pk int, products []string, options *GeneratePointCloudExportOptions) (*GenerateExportObject, *Response, error) {
+	return g.GeneratePointCloudExportWithContext(context.Background(), pk, products, options)
+}
+
+// GeneratePointCloudExportWithContext is like GeneratePointCloudExport, but the
+// request is bound to ctx.
+func (g *Grid) GeneratePointCloudExportWithContext(ctx context.Context, pk int, products []string, options *GeneratePointCloudExportOptions) (*GenerateExportObject, *Response, error) {
+	req, err := g.NewGeneratePointCloudExportRequest(ctx, pk, products, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	geo := new(GenerateExportObject)
+	resp, err := g.Do(req, geo)
+	if err != nil {
+		return nil, resp, err
+	}
+	return geo, resp, nil
+}
+
+/*
+NewGeneratePointCloudExportRequest returns the request that
+GeneratePointCloudExportWithContext sends, without sending it, so that it may
+be inspected, such as by a dry run. The options are validated as by
+GeneratePointCloudExport.
+*/
+func (g *Grid) NewGeneratePointCloudExportRequest(ctx context.Context, pk int, products []string, options *GeneratePointCloudExportOptions) (*http.Request, error) {
 	if options == nil {
 		options = NewGeneratePointCloudExportOptions()
 	}
+	if err := validateProducts(products); err != nil {
+		return nil, err
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
 	v := url.Values{}
-	// should check for empty products
 	prodstr := strings.Join(products, ",")
 	v.Add("products", prodstr)
 	if !options.Compressed {
@@ -600,10 +937,10 @@ func (g *Grid) GeneratePointCloudExport(pk int, products []string, options *Gene
 		v.Set("dim_classification", "False")
 	}
 	if options.FileExportOptions != "" {
-		v.Set("file_export_options", options.FileExportOptions)
+		v.Set("file_export_options", string(options.FileExportOptions))
 	}
 	if options.FileExportFormat != "" {
-		v.Set("file_export_format", options.FileExportFormat)
+		v.Set("file_export_format", string(options.FileExportFormat))
 	}
 	if options.GenerateDem {
 		v.Set("generate_dem", "True")
@@ -619,7 +956,7 @@ func (g *Grid) GeneratePointCloudExport(pk int, products []string, options *Gene
 		v.Set("intensity", "False")
 	}
 	if options.PclTerrain != "" {
-		v.Set("pcl_terrain", options.PclTerrain)
+		v.Set("pcl_terrain", string(options.PclTerrain))
 	}
 	if options.SendEmail {
 		v.Set("send_email", "True")
@@ -643,11 +980,88 @@ func (g *Grid) GeneratePointCloudExport(pk int, products []string, options *Gene
 	vals := v.Encode()
 	qurl := fmt.Sprintf("api/v2/aoi/%v/generate/pointcloud?%v", pk, vals)
 
-	req, err := g.NewRequest("GET", qurl, nil)
-	// fmt.Printf("%+v\n", req)
+	return g.NewRequestWithContext(ctx, "GET", qurl, nil)
+}
+
+/*
+NewGenerateRasterExportOptions is a factory method for a
+GenerateRasterExportOptions that provides all defaults
+*/
+func NewGenerateRasterExportOptions() *GenerateRasterExportOptions {
+	return &GenerateRasterExportOptions{
+		FileExportOptions: ExportIndividual,
+		FileExportFormat:  FormatGTiff,
+		Resampling:        ResampleNearest,
+		Compressed:        true,
+		SendEmail:         false,
+	}
+}
+
+/*
+GenerateRasterExport does just that for the given PK and set of raster
+products, as listed in AOIDetail.RasterIntersects. The options are checked
+with Validate, and an empty list of products is rejected, before anything is
+sent.
+
+GRiD API docs:
+https://github.com/CRREL/GRiD-API/blob/master/composed_api.rst#generate-raster-export
+*/
+func (g *Grid) GenerateRasterExport(pk int, products []string, options *GenerateRasterExportOptions) (*GenerateExportObject, *Response, error) {
+	return g.GenerateRasterExportWithContext(context.Background(), pk, products, options)
+}
+
+// GenerateRasterExportWithContext is like GenerateRasterExport, but the
+// request is bound to ctx.
+func (g *Grid) GenerateRasterExportWithContext(ctx context.Context, pk int, products []string, options *GenerateRasterExportOptions) (*GenerateExportObject, *Response, error) {
+	req, err := g.NewGenerateRasterExportRequest(ctx, pk, products, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	geo := new(GenerateExportObject)
 	resp, err := g.Do(req, geo)
-	return geo, resp, err
+	if err != nil {
+		return nil, resp, err
+	}
+	return geo, resp, nil
+}
+
+// NewGenerateRasterExportRequest returns the request that
+// GenerateRasterExportWithContext sends, without sending it. The options are
+// validated as by GenerateRasterExport.
+func (g *Grid) NewGenerateRasterExportRequest(ctx context.Context, pk int, products []string, options *GenerateRasterExportOptions) (*http.Request, error) {
+	if options == nil {
+		options = NewGenerateRasterExportOptions()
+	}
+	if err := validateProducts(products); err != nil {
+		return nil, err
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	v := url.Values{}
+	v.Add("products", strings.Join(products, ","))
+	if !options.Compressed {
+		v.Set("compressed", "False")
+	}
+	if options.FileExportOptions != "" {
+		v.Set("file_export_options", string(options.FileExportOptions))
+	}
+	if options.FileExportFormat != "" {
+		v.Set("file_export_format", string(options.FileExportFormat))
+	}
+	if options.Hsrs != "" {
+		v.Set("hsrs", options.Hsrs)
+	}
+	if options.Resampling != "" {
+		v.Set("resampling", string(options.Resampling))
+	}
+	if options.SendEmail {
+		v.Set("send_email", "True")
+	}
+	qurl := fmt.Sprintf("api/v2/aoi/%v/generate/raster?%v", pk, v.Encode())
+
+	return g.NewRequestWithContext(ctx, "GET", qurl, nil)
 }
 
 /*
@@ -657,11 +1071,23 @@ GRiD API docs:
 https://github.com/CRREL/GRiD-API/blob/master/composed_api.rst#generate-point-cloud-export
 */
 func (g *Grid) TaskDetails(pk string) (*TaskObject, *Response, error) {
-	taskObject := new(TaskObject)
+	return g.TaskDetailsWithContext(context.Background(), pk)
+}
+
+// TaskDetailsWithContext is like TaskDetails, but the request is bound to ctx.
+func (g *Grid) TaskDetailsWithContext(ctx context.Context, pk string) (*TaskObject, *Response, error) {
 	url := fmt.Sprintf("api/v2/task/%v/", pk)
-	req, err := g.NewRequest("GET", url, nil)
+	req, err := g.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	taskObject := new(TaskObject)
 	resp, err := g.Do(req, taskObject)
-	return taskObject, resp, err
+	if err != nil {
+		return nil, resp, err
+	}
+	return taskObject, resp, nil
 }
 
 // GetConfig extracts config file contents.
@@ -672,8 +1098,12 @@ func GetConfig() (Config, error) {
 	if err != nil {
 		return config, err
 	}
-	b, _ := ioutil.ReadAll(file)
-	json.Unmarshal(b, &config)
+	defer file.Close()
+
+	err = json.NewDecoder(file).Decode(&config)
+	if err != nil && err != io.EOF {
+		return config, fmt.Errorf("reading %v: %v", path, err)
+	}
 	return config, nil
 }
 
@@ -697,9 +1127,20 @@ func userHomeDir() string {
 	return os.Getenv("HOME")
 }
 
-// CreateConfigFile creates the config file for writing, overwriting existing.
+/*
+CreateConfigFile creates the config file for writing, overwriting existing. As
+it may hold credentials, the file is readable only by the user, including an
+existing file created with looser permissions.
+*/
 func CreateConfigFile() (*os.File, error) {
 	path := getConfigFilePath()
-	file, err := os.Create(path)
-	return file, err
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
 }
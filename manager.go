@@ -0,0 +1,239 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultWorkers is the number of files a DownloadManager downloads at once if
+// Workers is not set.
+const defaultWorkers = 4
+
+// ErrDownloadSkipped is reported for files that a DownloadManager did not
+// download, or did not finish downloading, because another file failed.
+var ErrDownloadSkipped = errors.New("download skipped after an earlier failure")
+
+/*
+A DownloadManager downloads many files into a directory at once, as by
+DownloadToDir, using a pool of workers. Partial files are kept on failure, so
+downloading the same files again resumes where they left off.
+
+The progress callbacks are called from the worker goroutines, but never
+concurrently, so they may update shared state such as a progress bar without
+locking. They must not block for long, as downloads stall while they run.
+*/
+type DownloadManager struct {
+	Grid *Grid  // client used to download files
+	Dir  string // directory the files are saved in; "" means the current directory
+
+	// Workers is the number of files downloaded at once. Zero means 4.
+	Workers int
+
+	// ContinueOnError downloads the remaining files when one fails. Otherwise,
+	// the first failure cancels the other downloads.
+	ContinueOnError bool
+
+	// Unzip unpacks each zip file downloaded into a directory alongside it,
+	// named for the file without its extension. The zip file is kept.
+	Unzip bool
+
+	// FileProgress, if non-nil, is called as each file is downloaded with the
+	// bytes saved so far and the file size, or -1 if the size is unknown.
+	FileProgress func(pk int, done, total int64)
+
+	// TotalProgress, if non-nil, is called as files are downloaded with the
+	// bytes saved so far across all files, and the total size of the files
+	// started so far. The total grows as each file starts, and is only the
+	// size of all the files once every file has started.
+	TotalProgress func(done, total int64)
+}
+
+// DownloadResult is the outcome of downloading a single file.
+type DownloadResult struct {
+	Pk       int       // primary key of the file
+	Info     *FileInfo // the downloaded file, if successful
+	Unzipped string    // directory the file was unpacked into, if it was
+	Err      error     // why the download failed, if it did
+}
+
+/*
+DownloadError reports the files that a DownloadManager failed to download. It
+unwraps to each of their errors, so errors.Is(err, ErrNotFound) reports whether
+any file was not found.
+*/
+type DownloadError struct {
+	Failed []DownloadResult // the failed downloads, excluding skipped files
+	Total  int              // number of files requested
+}
+
+func (e *DownloadError) Error() string {
+	msgs := make([]string, len(e.Failed))
+	for i, r := range e.Failed {
+		msgs[i] = fmt.Sprintf("%v: %v", r.Pk, r.Err)
+	}
+	return fmt.Sprintf("%v of %v downloads failed: %v", len(e.Failed), e.Total, strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed downloads.
+func (e *DownloadError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, r := range e.Failed {
+		errs[i] = r.Err
+	}
+	return errs
+}
+
+/*
+Download downloads the files specified by the user-provided primary keys. Each
+file is downloaded once, however many times it is given. Download returns a
+result for each file, in the order first given, and a *DownloadError if any
+download failed. If ctx is canceled, the remaining downloads fail with ctx's
+error.
+*/
+func (m *DownloadManager) Download(ctx context.Context, pks []int) ([]DownloadResult, error) {
+	workers := m.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	dir := m.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	pks = uniquePks(pks)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]DownloadResult, len(pks))
+	progress := newDownloadProgress(m)
+	jobs := make(chan int)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool // set once a download fails, unless ContinueOnError
+	)
+	for i := 0; i < workers && i < len(pks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				pk := pks[i]
+				results[i].Pk = pk
+				if ctx.Err() != nil {
+					results[i].Err = ctx.Err()
+					continue
+				}
+
+				info, _, err := m.Grid.downloadToDir(ctx, pk, dir, progress.file(pk))
+				results[i].Info = info
+				if err == nil && m.Unzip && isZip(info) {
+					results[i].Unzipped = unzipDir(info.Path)
+					if err = unzip(info.Path, results[i].Unzipped); err != nil {
+						results[i].Unzipped = ""
+					}
+				}
+				results[i].Err = err
+				if err == nil || m.ContinueOnError {
+					continue
+				}
+				mu.Lock()
+				if ctx.Err() == nil {
+					failed = true
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range pks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	dlErr := &DownloadError{Total: len(pks)}
+	for i, r := range results {
+		if r.Err == nil {
+			continue
+		}
+		if failed && errors.Is(r.Err, context.Canceled) {
+			// canceled by the failure, not by the caller
+			results[i].Err = ErrDownloadSkipped
+			continue
+		}
+		dlErr.Failed = append(dlErr.Failed, r)
+	}
+	if len(dlErr.Failed) > 0 {
+		return results, dlErr
+	}
+	return results, nil
+}
+
+// uniquePks returns pks without duplicates, in the order first given.
+func uniquePks(pks []int) []int {
+	seen := make(map[int]bool, len(pks))
+	unique := make([]int, 0, len(pks))
+	for _, pk := range pks {
+		if !seen[pk] {
+			seen[pk] = true
+			unique = append(unique, pk)
+		}
+	}
+	return unique
+}
+
+// downloadProgress serializes a DownloadManager's progress callbacks and keeps
+// the running totals.
+type downloadProgress struct {
+	m           *DownloadManager
+	mu          sync.Mutex
+	done, total int64
+}
+
+func newDownloadProgress(m *DownloadManager) *downloadProgress {
+	return &downloadProgress{m: m}
+}
+
+// file returns the progress callback for the download of pk.
+func (p *downloadProgress) file(pk int) func(done, total int64) {
+	if p.m.FileProgress == nil && p.m.TotalProgress == nil {
+		return nil
+	}
+	var lastDone, lastTotal int64
+	return func(done, total int64) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.done += done - lastDone
+		if total >= 0 {
+			p.total += total - lastTotal
+			lastTotal = total
+		}
+		lastDone = done
+
+		if p.m.FileProgress != nil {
+			p.m.FileProgress(pk, done, total)
+		}
+		if p.m.TotalProgress != nil {
+			p.m.TotalProgress(p.done, p.total)
+		}
+	}
+}
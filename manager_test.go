@@ -0,0 +1,139 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadManager(t *testing.T) {
+	g := newDownloadServer(t, "file-%d.zip")
+	dir := t.TempDir()
+
+	files := map[int]int64{}
+	var done, total int64
+	m := &DownloadManager{
+		Grid:    g,
+		Dir:     dir,
+		Workers: 3,
+		FileProgress: func(pk int, done, total int64) {
+			if total != int64(1000+pk) {
+				t.Errorf("Expected file %v to have size %v, got %v", pk, 1000+pk, total)
+			}
+			files[pk] = done
+		},
+		TotalProgress: func(d, t int64) { done, total = d, t },
+	}
+	pks := []int{1, 2, 3, 4, 5, 6, 7}
+	results, err := m.Download(context.Background(), pks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want int64
+	for i, pk := range pks {
+		want += int64(1000 + pk)
+		if results[i].Pk != pk || results[i].Info == nil {
+			t.Fatalf("Unexpected result %+v for %v", results[i], pk)
+		}
+		if results[i].Info.Path != filepath.Join(dir, results[i].Info.Name) {
+			t.Errorf("Unexpected path %v", results[i].Info.Path)
+		}
+		if files[pk] != int64(1000+pk) {
+			t.Errorf("Expected progress %v for %v, got %v", 1000+pk, pk, files[pk])
+		}
+	}
+	if done != want || total != want {
+		t.Errorf("Expected total progress %v of %v, got %v of %v", want, want, done, total)
+	}
+}
+
+func TestDownloadManagerDuplicates(t *testing.T) {
+	g := newDownloadServer(t, "file-%d.zip")
+	dir := t.TempDir()
+
+	m := &DownloadManager{Grid: g, Dir: dir, Workers: 4}
+	results, err := m.Download(context.Background(), []int{5, 5, 6, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Pk != 5 || results[1].Pk != 6 {
+		t.Fatalf("Expected a result for each distinct file, got %+v", results)
+	}
+	left, _ := filepath.Glob(filepath.Join(dir, ".grid-*"))
+	if len(left) != 0 {
+		t.Errorf("Expected no partial or lock files, got %v", left)
+	}
+}
+
+func TestDownloadManagerContinueOnError(t *testing.T) {
+	g := newDownloadServer(t, "file-%d.zip")
+	dir := t.TempDir()
+
+	m := &DownloadManager{Grid: g, Dir: dir, Workers: 2, ContinueOnError: true}
+	results, err := m.Download(context.Background(), []int{1, 404, 3})
+
+	var dlErr *DownloadError
+	if !errors.As(err, &dlErr) || len(dlErr.Failed) != 1 || dlErr.Failed[0].Pk != 404 || dlErr.Total != 3 {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v to wrap %v", err, ErrNotFound)
+	}
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Errorf("Expected the other downloads to succeed, got %v, %v", results[0].Err, results[2].Err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "file-3.zip")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDownloadManagerStopOnError(t *testing.T) {
+	g := newDownloadServer(t, "file-%d.zip")
+
+	m := &DownloadManager{Grid: g, Dir: t.TempDir(), Workers: 1}
+	results, err := m.Download(context.Background(), []int{404, 2, 3})
+
+	var dlErr *DownloadError
+	if !errors.As(err, &dlErr) || len(dlErr.Failed) != 1 {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	for _, r := range results[1:] {
+		if r.Err != ErrDownloadSkipped {
+			t.Errorf("Expected %v to be skipped, got %v", r.Pk, r.Err)
+		}
+	}
+}
+
+func TestDownloadManagerCanceled(t *testing.T) {
+	g := newDownloadServer(t, "file-%d.zip")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m := &DownloadManager{Grid: g, Dir: t.TempDir()}
+	results, err := m.Download(ctx, []int{1, 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	for _, r := range results {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("Expected %v, got %v", context.Canceled, r.Err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// redacted replaces credentials in requests seen by Redacted middleware.
+	redacted = "REDACTED"
+
+	// maxTraceBody is the most of each body written by TraceMiddleware.
+	maxTraceBody = 1024
+)
+
+// A Doer sends an HTTP request and returns its response. *http.Client is a
+// Doer.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts an ordinary function to a Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+/*
+Middleware wraps a Doer with additional behavior, such as adding headers or
+logging. It must call next to send the request, and return next's response
+unless it deliberately replaces it.
+*/
+type Middleware func(next Doer) Doer
+
+/*
+Use appends mw to the client's middleware chain. The chain runs around each
+attempt that Do sends, inside the retry, rate limit and in-flight handling,
+with the first middleware added being the outermost. Use must not be called
+while requests are in progress.
+*/
+func (g *Grid) Use(mw ...Middleware) {
+	g.middleware = append(g.middleware, mw...)
+}
+
+// WithMiddleware appends mw to the client's middleware chain, as by Use.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(g *Grid) error {
+		g.Use(mw...)
+		return nil
+	}
+}
+
+// chain wraps d in the client's middleware.
+func (g *Grid) chain(d Doer) Doer {
+	for i := len(g.middleware) - 1; i >= 0; i-- {
+		d = g.middleware[i](d)
+	}
+	return d
+}
+
+/*
+LoggingMiddleware logs every request to logger: the method, the URL with
+credentials removed, the status code and the latency. Failed requests are
+logged at the error level.
+*/
+func LoggingMiddleware(logger *slog.Logger) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.Do(req)
+			attrs := []any{
+				slog.String("method", req.Method),
+				slog.String("url", SanitizeURL(req.URL).String()),
+				slog.Duration("latency", time.Since(start)),
+			}
+			if err != nil {
+				logger.ErrorContext(req.Context(), "GRiD request failed", append(attrs, slog.Any("error", err))...)
+				return resp, err
+			}
+			logger.InfoContext(req.Context(), "GRiD request", append(attrs, slog.Int("status", resp.StatusCode))...)
+			return resp, err
+		})
+	}
+}
+
+/*
+TraceMiddleware writes a dump of every request and response to w, for
+debugging: the method, the URL with credentials removed, the status, the
+latency, and up to the first 1 KiB of each text or JSON body. Bodies are read
+without being consumed, so the trace does not change what the caller receives,
+and downloads are not dumped.
+*/
+func TraceMiddleware(w io.Writer) Middleware {
+	var mu sync.Mutex
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			u := SanitizeURL(req.URL)
+
+			var reqBody []byte
+			if req.GetBody != nil {
+				if body, err := req.GetBody(); err == nil {
+					reqBody, _ = ioutil.ReadAll(io.LimitReader(body, maxTraceBody+1))
+					body.Close()
+				}
+			}
+			mu.Lock()
+			fmt.Fprintf(w, "--> %v %v\n%v", req.Method, u, traceBody(reqBody))
+			mu.Unlock()
+
+			start := time.Now()
+			resp, err := next.Do(req)
+			latency := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				mu.Lock()
+				fmt.Fprintf(w, "<-- %v %v: %v (%v)\n", req.Method, u, err, latency)
+				mu.Unlock()
+				return resp, err
+			}
+
+			body := "    (" + resp.Header.Get("Content-Type") + " body not shown)\n"
+			if isText(resp.Header.Get("Content-Type")) {
+				// peek at the start of the body, then put it back
+				peek, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxTraceBody+1))
+				resp.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(peek), resp.Body), resp.Body}
+				body = traceBody(peek)
+			}
+
+			mu.Lock()
+			fmt.Fprintf(w, "<-- %v %v %v (%v)\n%v", resp.Status, req.Method, u, latency, body)
+			mu.Unlock()
+			return resp, err
+		})
+	}
+}
+
+// isText reports whether a body of the given content type is worth tracing.
+func isText(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "" || strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/x-www-form-urlencoded"
+}
+
+// traceBody formats a body for TraceMiddleware, truncating it if necessary.
+func traceBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	suffix := ""
+	if len(body) > maxTraceBody {
+		body, suffix = body[:maxTraceBody], "..."
+	}
+	text := strings.TrimRight(string(body), "\n")
+	return "    " + strings.Replace(text, "\n", "\n    ", -1) + suffix + "\n"
+}
+
+/*
+Redacted wraps mw so that it only ever sees a copy of the request with the
+Authorization header and the source API key replaced by "REDACTED". This makes
+it safe to pass third-party audit or logging middleware credentials-bearing
+requests. The request sent to GRiD still carries the real credentials, along
+with any other changes mw makes.
+*/
+func Redacted(mw Middleware) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			auth := req.Header.Values("Authorization")
+			source := req.URL.Query().Get("source")
+
+			inner := mw(DoerFunc(func(r *http.Request) (*http.Response, error) {
+				// restore the credentials that mw never saw
+				r = r.Clone(r.Context())
+				if r.Header.Get("Authorization") == redacted {
+					r.Header["Authorization"] = auth
+				}
+				if q := r.URL.Query(); q.Get("source") == redacted {
+					q.Set("source", source)
+					r.URL.RawQuery = q.Encode()
+				}
+				return next.Do(r)
+			}))
+			return inner.Do(redactRequest(req))
+		})
+	}
+}
+
+// redactRequest returns a copy of req with its credentials redacted.
+func redactRequest(req *http.Request) *http.Request {
+	r := req.Clone(req.Context())
+	if r.Header.Get("Authorization") != "" {
+		r.Header.Set("Authorization", redacted)
+	}
+	r.URL = SanitizeURL(req.URL)
+	return r
+}
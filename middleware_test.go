@@ -0,0 +1,131 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var header string
+
+	tag := func(name string) Middleware {
+		return func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Add("X-Trace", name)
+				return next.Do(req)
+			})
+		}
+	}
+
+	g := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Trace")
+		w.Write([]byte(`{}`))
+	}), WithMiddleware(tag("a")))
+	g.Use(tag("b"))
+	if _, _, err := g.GetExport(1); err != nil {
+		t.Fatal(err)
+	}
+	if header != "a" {
+		t.Errorf("Expected the first middleware added to run first, got %q", header)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	var auth, source, audit string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		source = r.URL.Query().Get("source")
+		audit = r.Header.Get("X-Audit")
+		w.Write([]byte(`{}`))
+	})
+
+	var seenAuth, seenURL string
+	spy := func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			seenAuth = req.Header.Get("Authorization")
+			seenURL = req.URL.String()
+			req.Header.Set("X-Audit", "yes")
+			return next.Do(req)
+		})
+	}
+
+	g := newTestClient(t, handler,
+		WithBasicAuth("johnsmith", "password"),
+		WithAPIKey("MyAPI-key"),
+		WithMiddleware(Redacted(spy)),
+	)
+	if _, _, err := g.GetExport(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if seenAuth != redacted || strings.Contains(seenURL, "MyAPI-key") {
+		t.Errorf("Middleware saw credentials: %q, %q", seenAuth, seenURL)
+	}
+	if !strings.HasPrefix(auth, "Basic ") || source != "MyAPI-key" {
+		t.Errorf("Server did not receive credentials: %q, %q", auth, source)
+	}
+	if audit != "yes" {
+		t.Error("Server did not receive the header added by the middleware")
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	g := newTestClient(t, statusHandler(http.StatusNotFound, `{"detail": "Not found."}`))
+
+	var buf bytes.Buffer
+	g.Use(LoggingMiddleware(slog.New(slog.NewTextHandler(&buf, nil))))
+	g.APIKey = "MyAPI-key"
+	g.GetAOI(1)
+
+	out := buf.String()
+	if !strings.Contains(out, "status=404") || !strings.Contains(out, "method=GET") {
+		t.Errorf("Unexpected log output %q", out)
+	}
+	if strings.Contains(out, "MyAPI-key") {
+		t.Errorf("API key leaked in log output %q", out)
+	}
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	body := `{"detail": "` + strings.Repeat("x", 2*maxTraceBody) + `"}`
+	g := newTestClient(t, statusHandler(http.StatusNotFound, body))
+
+	var buf bytes.Buffer
+	g.Use(TraceMiddleware(&buf))
+	g.APIKey = "MyAPI-key"
+	_, _, err := g.GetAOI(1)
+
+	var errResp *ErrorResponse
+	if !errors.As(err, &errResp) || string(errResp.Body) != body {
+		t.Error("Tracing consumed the response body")
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "--> GET ") || !strings.Contains(out, "<-- 404 Not Found GET ") {
+		t.Errorf("Unexpected trace output %q", out)
+	}
+	if !strings.Contains(out, "...") || len(out) > 2*maxTraceBody {
+		t.Errorf("Expected the body to be truncated, got %v bytes", len(out))
+	}
+	if strings.Contains(out, "MyAPI-key") {
+		t.Errorf("API key leaked in trace output %q", out)
+	}
+}
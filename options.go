@@ -0,0 +1,107 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// An Option configures a Grid client created by NewClient or New.
+type Option func(*Grid) error
+
+// WithBaseURL sets the base URL for API requests. A trailing slash is added if
+// it is missing.
+func WithBaseURL(baseURL string) Option {
+	return func(g *Grid) error {
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+		g.BaseURL = u
+		return nil
+	}
+}
+
+// WithBasicAuth authenticates requests with the given GRiD username and
+// password.
+func WithBasicAuth(username, password string) Option {
+	return WithAuthenticator(&BasicAuth{Username: username, Password: password})
+}
+
+// WithHTTPClient sets the HTTP client used to send requests. The client is
+// copied, so later options such as WithTimeout do not modify the caller's
+// client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(g *Grid) error {
+		if client == nil {
+			return errors.New("HTTP client must be non-nil")
+		}
+		g.HTTPClient = client
+		return nil
+	}
+}
+
+// WithTransport sets the transport used to send requests, taking precedence
+// over the transport of any client given to WithHTTPClient.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(g *Grid) error {
+		g.Transport = transport
+		return nil
+	}
+}
+
+// WithAPIKey sets the API key sent as the source parameter of every request.
+func WithAPIKey(key string) Option {
+	return func(g *Grid) error {
+		g.APIKey = key
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(g *Grid) error {
+		g.UserAgent = ua
+		return nil
+	}
+}
+
+// WithTimeout limits the time taken by each request, including reading the
+// response body. A timeout of zero means no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(g *Grid) error {
+		if timeout < 0 {
+			return errors.New("timeout must not be negative")
+		}
+		g.timeout = timeout
+		return nil
+	}
+}
+
+// withAuth sets the pre-encoded Basic authorization string, as stored in the
+// config file.
+func withAuth(auth string) Option {
+	return func(g *Grid) error {
+		g.Auth = auth
+		return nil
+	}
+}
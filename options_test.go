@@ -0,0 +1,94 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewClientDefaults(t *testing.T) {
+	g, err := NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.BaseURL.String() != defaultBaseURL {
+		t.Errorf("Expected base URL %v, got %v", defaultBaseURL, g.BaseURL)
+	}
+	if g.APIKey != apiKey {
+		t.Errorf("Expected API key %v, got %v", apiKey, g.APIKey)
+	}
+	if g.HTTPClient == nil {
+		t.Error("Expected an HTTP client")
+	}
+}
+
+func TestNewClientOptions(t *testing.T) {
+	var auth, ua, source string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		ua = r.Header.Get("User-Agent")
+		source = r.URL.Query().Get("source")
+		w.Write([]byte(`{"task_id": "abc"}`))
+	}))
+	defer ts.Close()
+
+	hc := &http.Client{}
+	g, err := NewClient(
+		WithBaseURL(ts.URL),
+		WithBasicAuth("johnsmith", "password"),
+		WithHTTPClient(hc),
+		WithAPIKey("MyAPI-key"),
+		WithUserAgent("test-agent"),
+		WithTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hc.Timeout != 0 {
+		t.Error("WithTimeout modified the caller's HTTP client")
+	}
+	if g.HTTPClient.Timeout != time.Second {
+		t.Errorf("Expected timeout %v, got %v", time.Second, g.HTTPClient.Timeout)
+	}
+
+	task, _, err := g.TaskDetails("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.TaskID != "abc" {
+		t.Errorf("Expected task ID abc, got %v", task.TaskID)
+	}
+	if auth != "Basic am9obnNtaXRoOnBhc3N3b3Jk" {
+		t.Errorf("Unexpected Authorization header %q", auth)
+	}
+	if ua != "test-agent" {
+		t.Errorf("Unexpected User-Agent header %q", ua)
+	}
+	if source != "MyAPI-key" {
+		t.Errorf("Unexpected source parameter %q", source)
+	}
+}
+
+func TestNewClientInvalidOption(t *testing.T) {
+	if _, err := NewClient(WithTimeout(-time.Second)); err == nil {
+		t.Error("Should have received error")
+	}
+	if _, err := NewClient(WithHTTPClient(nil)); err == nil {
+		t.Error("Should have received error")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FileExportOption selects whether the collects of an export are exported as
+// individual files or merged into one.
+type FileExportOption string
+
+// The file export options supported by GRiD.
+const (
+	ExportIndividual FileExportOption = "individual"
+	ExportCollect    FileExportOption = "collect"
+)
+
+// FileExportFormat is the file format of a point cloud export.
+type FileExportFormat string
+
+// The point cloud formats supported by GRiD.
+const (
+	FormatLAS12 FileExportFormat = "las12"
+	FormatLAS13 FileExportFormat = "las13"
+	FormatLAS14 FileExportFormat = "las14"
+)
+
+// PclTerrain is the type of terrain used to tune the ground filter of a point
+// cloud export.
+type PclTerrain string
+
+// The terrain types supported by GRiD.
+const (
+	TerrainUrban       PclTerrain = "urban"
+	TerrainMountainous PclTerrain = "mountainous"
+	TerrainSuburban    PclTerrain = "suburban"
+	TerrainFoliated    PclTerrain = "foliated"
+)
+
+/*
+OptionError reports an export option that GRiD would reject. It unwraps to
+ErrValidation, so errors.Is(err, ErrValidation) reports both invalid options
+found before a request is sent and those rejected by GRiD.
+*/
+type OptionError struct {
+	Option string      // name of the option, as sent to GRiD
+	Value  interface{} // the invalid value
+	Reason string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("invalid %v %v: %v", e.Option, e.Value, e.Reason)
+}
+
+// Unwrap returns ErrValidation.
+func (e *OptionError) Unwrap() error {
+	return ErrValidation
+}
+
+/*
+Validate checks the options for values that GRiD would reject: unknown file
+export options, formats or terrain types, an HSRS that is not a numeric EPSG
+code, negative sizes, and options that depend on others, such as a cell
+spacing without DEM generation. It returns an *OptionError for each problem,
+joined with errors.Join, or nil if the options are valid.
+*/
+func (o *GeneratePointCloudExportOptions) Validate() error {
+	var errs []error
+	invalid := func(option string, value interface{}, reason string) {
+		errs = append(errs, &OptionError{Option: option, Value: value, Reason: reason})
+	}
+
+	switch o.FileExportOptions {
+	case "", ExportIndividual, ExportCollect:
+	default:
+		invalid("file_export_options", strconv.Quote(string(o.FileExportOptions)), oneOf(ExportIndividual, ExportCollect))
+	}
+	switch o.FileExportFormat {
+	case "", FormatLAS12, FormatLAS13, FormatLAS14:
+	default:
+		invalid("file_export_format", strconv.Quote(string(o.FileExportFormat)), oneOf(FormatLAS12, FormatLAS13, FormatLAS14))
+	}
+	switch o.PclTerrain {
+	case "", TerrainUrban, TerrainMountainous, TerrainSuburban, TerrainFoliated:
+	default:
+		invalid("pcl_terrain", strconv.Quote(string(o.PclTerrain)), oneOf(TerrainUrban, TerrainMountainous, TerrainSuburban, TerrainFoliated))
+	}
+	if o.Hsrs != "" && !isEPSGCode(o.Hsrs) {
+		invalid("hsrs", strconv.Quote(o.Hsrs), "must be a numeric EPSG code, such as 4326")
+	}
+
+	if o.GenerateDem && o.CellSpacing <= 0 {
+		invalid("cell_spacing", o.CellSpacing, "must be positive")
+	}
+	if !o.GenerateDem && o.CellSpacing != 0 && o.CellSpacing != 1.0 {
+		invalid("cell_spacing", o.CellSpacing, "requires generate_dem")
+	}
+	if o.SriHResolution < 0 {
+		invalid("sri_hres", o.SriHResolution, "must not be negative")
+	}
+	if o.DecimationRadius < 0 {
+		invalid("decimation_radius", o.DecimationRadius, "must not be negative")
+	}
+	if o.RetileSize < 0 {
+		invalid("retile_size", o.RetileSize, "must not be negative")
+	}
+	if o.RetileArea < 0 {
+		invalid("retile_area", o.RetileArea, "must not be negative")
+	}
+	if o.RetileSize > 0 && o.RetileArea > 0 {
+		invalid("retile_area", o.RetileArea, "cannot be combined with retile_size")
+	}
+	return errors.Join(errs...)
+}
+
+// validateProducts checks that at least one collect is to be exported.
+func validateProducts(products []string) error {
+	if len(products) == 0 {
+		return &OptionError{Option: "products", Value: "[]", Reason: "at least one collect is required"}
+	}
+	for _, p := range products {
+		if strings.TrimSpace(p) == "" {
+			return &OptionError{Option: "products", Value: strconv.Quote(p), Reason: "collects must not be empty"}
+		}
+	}
+	return nil
+}
+
+// isEPSGCode reports whether s is a numeric EPSG code.
+func isEPSGCode(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n > 0
+}
+
+// oneOf describes the valid values of an enum option.
+func oneOf[T ~string](values ...T) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = string(v)
+	}
+	return "must be one of " + strings.Join(s, ", ")
+}
@@ -0,0 +1,130 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGeneratePointCloudExportOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *GeneratePointCloudExportOptions)
+		option string // the option reported as invalid, if any
+	}{
+		{"defaults", func(o *GeneratePointCloudExportOptions) {}, ""},
+		{"dem", func(o *GeneratePointCloudExportOptions) { o.GenerateDem, o.CellSpacing = true, 0.5 }, ""},
+		{"all", func(o *GeneratePointCloudExportOptions) {
+			o.Hsrs, o.FileExportOptions, o.FileExportFormat, o.PclTerrain = "32614", ExportCollect, FormatLAS14, TerrainFoliated
+			o.DecimationRadius, o.RetileSize = 2, 500
+		}, ""},
+		{"terrain", func(o *GeneratePointCloudExportOptions) { o.PclTerrain = "urbn" }, "pcl_terrain"},
+		{"format", func(o *GeneratePointCloudExportOptions) { o.FileExportFormat = "las" }, "file_export_format"},
+		{"file export options", func(o *GeneratePointCloudExportOptions) { o.FileExportOptions = "merged" }, "file_export_options"},
+		{"hsrs", func(o *GeneratePointCloudExportOptions) { o.Hsrs = "EPSG:4326" }, "hsrs"},
+		{"cell spacing without dem", func(o *GeneratePointCloudExportOptions) { o.CellSpacing = 2 }, "cell_spacing"},
+		{"zero cell spacing", func(o *GeneratePointCloudExportOptions) { o.GenerateDem, o.CellSpacing = true, 0 }, "cell_spacing"},
+		{"negative radius", func(o *GeneratePointCloudExportOptions) { o.DecimationRadius = -1 }, "decimation_radius"},
+		{"retile size and area", func(o *GeneratePointCloudExportOptions) { o.RetileSize, o.RetileArea = 500, 1000 }, "retile_area"},
+	}
+	for _, tt := range tests {
+		o := NewGeneratePointCloudExportOptions()
+		tt.modify(o)
+		err := o.Validate()
+
+		if tt.option == "" {
+			if err != nil {
+				t.Errorf("%v: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		var optErr *OptionError
+		if !errors.As(err, &optErr) || optErr.Option != tt.option || !errors.Is(err, ErrValidation) {
+			t.Errorf("%v: expected an invalid %v, got %v", tt.name, tt.option, err)
+		}
+	}
+}
+
+func TestGeneratePointCloudExportOptionsValidateAll(t *testing.T) {
+	o := NewGeneratePointCloudExportOptions()
+	o.PclTerrain, o.Hsrs = "urbn", "WGS84"
+	err := o.Validate()
+	if err == nil || !strings.Contains(err.Error(), "pcl_terrain") || !strings.Contains(err.Error(), "hsrs") {
+		t.Errorf("Expected every problem to be reported, got %v", err)
+	}
+}
+
+func TestGeneratePointCloudExportInvalid(t *testing.T) {
+	requests := 0
+	g := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"task_id": "abc", "export_id": 303}`))
+	}))
+
+	invalid := NewGeneratePointCloudExportOptions()
+	invalid.PclTerrain = "urbn"
+	if _, _, err := g.GeneratePointCloudExport(1, []string{"201"}, invalid); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected %v, got %v", ErrValidation, err)
+	}
+	if _, _, err := g.GeneratePointCloudExport(1, nil, nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected %v, got %v", ErrValidation, err)
+	}
+	if _, _, err := g.GenerateRasterExport(1, []string{}, nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected %v, got %v", ErrValidation, err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests to be sent, got %v", requests)
+	}
+
+	if _, _, err := g.GeneratePointCloudExport(1, []string{"201"}, nil); err != nil || requests != 1 {
+		t.Errorf("Expected a valid export to be sent, got %v", err)
+	}
+}
+
+func TestNewGeneratePointCloudExportRequest(t *testing.T) {
+	g, _ := NewClient(WithBaseURL("http://grid.example/"), WithAPIKey("key"))
+
+	options := NewGeneratePointCloudExportOptions()
+	if err := json.Unmarshal([]byte(`{"file_export_format": "las14", "pcl_terrain": "urban", "intensity": false}`), options); err != nil {
+		t.Fatal(err)
+	}
+	if !options.Compressed {
+		t.Error("Expected defaults not in the JSON to be kept")
+	}
+	req, err := g.NewGeneratePointCloudExportRequest(context.Background(), 1, []string{"201"}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "GET" || req.URL.Path != "/api/v2/aoi/1/generate/pointcloud" {
+		t.Errorf("Unexpected request %v %v", req.Method, req.URL)
+	}
+	q := req.URL.Query()
+	want := map[string]string{"file_export_format": "las14", "pcl_terrain": "urban", "intensity": "False", "products": "201"}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("Expected %v=%v, got %q", k, v, q.Get(k))
+		}
+	}
+
+	options.PclTerrain = "urbn"
+	if _, err := g.NewGeneratePointCloudExportRequest(context.Background(), 1, []string{"201"}, options); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected %v, got %v", ErrValidation, err)
+	}
+}
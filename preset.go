@@ -0,0 +1,127 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// presetExt is the extension of preset files.
+const presetExt = ".json"
+
+// presetName matches valid preset names, which are used as file names.
+var presetName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+/*
+PresetDir returns the directory presets are saved in, the presets directory
+alongside the config file. Each preset is a JSON file named for the preset,
+holding GeneratePointCloudExportOptions keyed by the GRiD parameter names.
+*/
+func PresetDir() string {
+	return filepath.Join(filepath.Dir(getConfigFilePath()), "presets")
+}
+
+func presetPath(name string) (string, error) {
+	if !presetName.MatchString(name) {
+		return "", fmt.Errorf("invalid preset name %q: must be letters, digits, '.', '_' or '-'", name)
+	}
+	return filepath.Join(PresetDir(), name+presetExt), nil
+}
+
+/*
+LoadPreset returns the named export preset. Options the preset does not set
+have their default values, as returned by NewGeneratePointCloudExportOptions.
+The options are checked with Validate, and unknown options are rejected. If
+there is no such preset, the error satisfies os.IsNotExist.
+*/
+func LoadPreset(name string) (*GeneratePointCloudExportOptions, error) {
+	path, err := presetPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	options := NewGeneratePointCloudExportOptions()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(options); err != nil {
+		return nil, fmt.Errorf("reading %v: %v", path, err)
+	}
+	if err := options.Validate(); err != nil {
+		return nil, fmt.Errorf("preset %v: %w", name, err)
+	}
+	return options, nil
+}
+
+// SavePreset validates the options and saves them as the named preset,
+// replacing any existing preset of that name.
+func SavePreset(name string, options *GeneratePointCloudExportOptions) error {
+	path, err := presetPath(name)
+	if err != nil {
+		return err
+	}
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(options, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(PresetDir(), 0777); err != nil {
+		return err
+	}
+	return writeFileAtomic(PresetDir(), filepath.Base(path), append(data, '\n'))
+}
+
+// ListPresets returns the names of the saved presets, in order.
+func ListPresets() ([]string, error) {
+	files, err := ioutil.ReadDir(PresetDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		name := strings.TrimSuffix(f.Name(), presetExt)
+		if f.Mode().IsRegular() && strings.HasSuffix(f.Name(), presetExt) && presetName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// DeletePreset deletes the named preset. If there is no such preset, the error
+// satisfies os.IsNotExist.
+func DeletePreset(name string) error {
+	path, err := presetPath(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
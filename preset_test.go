@@ -0,0 +1,107 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if names, err := ListPresets(); err != nil || len(names) != 0 {
+		t.Fatalf("Expected no presets, got %v, %v", names, err)
+	}
+
+	dem := NewGeneratePointCloudExportOptions()
+	dem.Hsrs = "32618"
+	dem.GenerateDem = true
+	dem.CellSpacing = 1
+	preview := NewGeneratePointCloudExportOptions()
+	preview.DecimationRadius = 2.5
+	if err := SavePreset("dem-1m", dem); err != nil {
+		t.Fatal(err)
+	}
+	if err := SavePreset("preview", preview); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := ListPresets()
+	if err != nil || !reflect.DeepEqual(names, []string{"dem-1m", "preview"}) {
+		t.Errorf("Unexpected presets %v, %v", names, err)
+	}
+	got, err := LoadPreset("dem-1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, dem) {
+		t.Errorf("Expected %+v, got %+v", dem, got)
+	}
+
+	if err := DeletePreset("preview"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPreset("preview"); !os.IsNotExist(err) {
+		t.Errorf("Expected the preset to be deleted, got %v", err)
+	}
+	if err := DeletePreset("preview"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error, got %v", err)
+	}
+}
+
+func TestLoadPresetDefaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	os.MkdirAll(PresetDir(), 0777)
+	ioutil.WriteFile(filepath.Join(PresetDir(), "urban.json"), []byte(`{"pcl_terrain": "urban"}`), 0644)
+	ioutil.WriteFile(filepath.Join(PresetDir(), "typo.json"), []byte(`{"pcl_terain": "urban"}`), 0644)
+	ioutil.WriteFile(filepath.Join(PresetDir(), "invalid.json"), []byte(`{"pcl_terrain": "urbn"}`), 0644)
+
+	got, err := LoadPreset("urban")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewGeneratePointCloudExportOptions()
+	want.PclTerrain = TerrainUrban
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	if _, err := LoadPreset("typo"); err == nil {
+		t.Error("Expected an unknown option to be rejected")
+	}
+	if _, err := LoadPreset("invalid"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected %v, got %v", ErrValidation, err)
+	}
+}
+
+func TestPresetNames(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	options := NewGeneratePointCloudExportOptions()
+	for _, name := range []string{"", "../config", "a/b", ".hidden"} {
+		if err := SavePreset(name, options); err == nil {
+			t.Errorf("Expected preset name %q to be rejected", name)
+		}
+	}
+	invalid := NewGeneratePointCloudExportOptions()
+	invalid.Hsrs = "WGS84"
+	if err := SavePreset("invalid", invalid); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected %v, got %v", ErrValidation, err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"errors"
+	"strconv"
+)
+
+// RasterFormat is the file format of a raster export.
+type RasterFormat string
+
+// The raster formats supported by GRiD.
+const (
+	FormatGTiff   RasterFormat = "GTiff"
+	FormatNITF    RasterFormat = "NITF"
+	FormatHFA     RasterFormat = "HFA"
+	FormatAAIGrid RasterFormat = "AAIGrid"
+)
+
+// Resampling is the method used to resample a raster export.
+type Resampling string
+
+// The resampling methods supported by GRiD.
+const (
+	ResampleNearest  Resampling = "nearest"
+	ResampleBilinear Resampling = "bilinear"
+	ResampleCubic    Resampling = "cubic"
+	ResampleAverage  Resampling = "average"
+)
+
+/*
+Validate checks the options for values that GRiD would reject: unknown file
+export options, formats or resampling methods, and an HSRS that is not a
+numeric EPSG code. It returns an *OptionError for each problem, joined with
+errors.Join, or nil if the options are valid.
+*/
+func (o *GenerateRasterExportOptions) Validate() error {
+	var errs []error
+	invalid := func(option string, value interface{}, reason string) {
+		errs = append(errs, &OptionError{Option: option, Value: value, Reason: reason})
+	}
+
+	switch o.FileExportOptions {
+	case "", ExportIndividual, ExportCollect:
+	default:
+		invalid("file_export_options", strconv.Quote(string(o.FileExportOptions)), oneOf(ExportIndividual, ExportCollect))
+	}
+	switch o.FileExportFormat {
+	case "", FormatGTiff, FormatNITF, FormatHFA, FormatAAIGrid:
+	default:
+		invalid("file_export_format", strconv.Quote(string(o.FileExportFormat)), oneOf(FormatGTiff, FormatNITF, FormatHFA, FormatAAIGrid))
+	}
+	switch o.Resampling {
+	case "", ResampleNearest, ResampleBilinear, ResampleCubic, ResampleAverage:
+	default:
+		invalid("resampling", strconv.Quote(string(o.Resampling)), oneOf(ResampleNearest, ResampleBilinear, ResampleCubic, ResampleAverage))
+	}
+	if o.Hsrs != "" && !isEPSGCode(o.Hsrs) {
+		invalid("hsrs", strconv.Quote(o.Hsrs), "must be a numeric EPSG code, such as 4326")
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestGenerateRasterExportOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *GenerateRasterExportOptions)
+		option string // the option reported as invalid, if any
+	}{
+		{"defaults", func(o *GenerateRasterExportOptions) {}, ""},
+		{"all", func(o *GenerateRasterExportOptions) {
+			o.Hsrs, o.FileExportOptions, o.FileExportFormat, o.Resampling = "32614", ExportCollect, FormatNITF, ResampleCubic
+		}, ""},
+		{"format", func(o *GenerateRasterExportOptions) { o.FileExportFormat = "tiff" }, "file_export_format"},
+		{"point cloud format", func(o *GenerateRasterExportOptions) { o.FileExportFormat = RasterFormat(FormatLAS14) }, "file_export_format"},
+		{"resampling", func(o *GenerateRasterExportOptions) { o.Resampling = "lanczos" }, "resampling"},
+		{"file export options", func(o *GenerateRasterExportOptions) { o.FileExportOptions = "merged" }, "file_export_options"},
+		{"hsrs", func(o *GenerateRasterExportOptions) { o.Hsrs = "EPSG:4326" }, "hsrs"},
+	}
+	for _, tt := range tests {
+		o := NewGenerateRasterExportOptions()
+		tt.modify(o)
+		err := o.Validate()
+
+		if tt.option == "" {
+			if err != nil {
+				t.Errorf("%v: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		var optErr *OptionError
+		if !errors.As(err, &optErr) || optErr.Option != tt.option || !errors.Is(err, ErrValidation) {
+			t.Errorf("%v: expected an invalid %v, got %v", tt.name, tt.option, err)
+		}
+	}
+}
+
+func TestGenerateRasterExportInvalid(t *testing.T) {
+	requests := 0
+	g := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"task_id": "abc", "export_id": 303}`))
+	}))
+
+	options := NewGenerateRasterExportOptions()
+	options.Resampling = "lanczos"
+	if _, _, err := g.GenerateRasterExport(1, []string{"202"}, options); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected %v, got %v", ErrValidation, err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests to be sent, got %v", requests)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+/*
+RetryPolicy controls how Do retries requests that fail with a transient error,
+either a dropped connection or one of the RetryableStatus codes.
+
+Only GET and HEAD requests, and read-only POSTs such as the one sent by Lookup,
+are retried unless RetryAllMethods is set, and a request with a body is only retried if the body can be rewound (see
+http.Request.GetBody).
+*/
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values
+	// less than 2 disable retries.
+	MaxAttempts int
+
+	// MinBackoff is the delay before the first retry. Each subsequent delay is
+	// doubled, up to MaxBackoff. A response whose Retry-After header asks for
+	// a longer delay than MaxBackoff is returned instead of being retried.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Jitter randomly spreads each delay by up to this fraction of itself, and
+	// should be between 0 and 1.
+	Jitter float64
+
+	// RetryableStatus lists the response status codes that are retried.
+	RetryableStatus []int
+
+	// RetryAllMethods allows non-idempotent requests, such as POST, to be
+	// retried.
+	RetryAllMethods bool
+}
+
+// DefaultRetryPolicy returns the retry policy used by NewClient and New.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		Jitter:      0.2,
+		RetryableStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithRetryPolicy sets the client's retry policy. A nil policy disables
+// retries.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(g *Grid) error {
+		g.RetryPolicy = policy
+		return nil
+	}
+}
+
+// readOnlyKey marks the context of a request that changes nothing on the
+// server, though it is not a GET.
+type readOnlyKey struct{}
+
+// readOnly returns a copy of ctx whose requests may be retried like a GET,
+// such as a lookup sent as a POST only to keep its geometry out of the URL.
+func readOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// shouldRetry reports whether the outcome of the given attempt warrants
+// another.
+func (p *RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error, attempt int) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+	if !p.RetryAllMethods && req.Method != "GET" && req.Method != "HEAD" && req.Context().Value(readOnlyKey{}) == nil {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		// a certificate that failed verification will fail again
+		var certErr *tls.CertificateVerificationError
+		return !errors.As(err, &certErr)
+	}
+	for _, code := range p.RetryableStatus {
+		if resp.StatusCode == code {
+			// don't wait longer than MaxBackoff for the server
+			ra, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+			return !ok || p.MaxBackoff <= 0 || ra <= p.MaxBackoff
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the retry following the given attempt. A
+// Retry-After header on resp is honored if it asks for a longer delay, which
+// shouldRetry limits to MaxBackoff.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	if resp != nil {
+		if ra, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok && ra > d {
+			d = ra
+		}
+	}
+	return d
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an
+// HTTP date.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+/*
+send sends req, retrying according to g.RetryPolicy, and returns the final
+response along with the number of attempts made. Each attempt first waits on
+the client's rate limit and in-flight cap, and is then sent through the
+client's middleware. The caller is responsible for closing the response body.
+*/
+func (g *Grid) send(req *http.Request) (*http.Response, int, error) {
+	ctx := req.Context()
+
+	var client Doer = g.HTTPClient
+	if g.HTTPClient == nil {
+		client = &http.Client{
+			Transport: g.Transport,
+		}
+	}
+	client = g.chain(client)
+
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, attempt - 1, err
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+
+		release, err := g.acquire(ctx)
+		if err != nil {
+			return nil, attempt - 1, err
+		}
+		resp, err := client.Do(r)
+		if err != nil {
+			release()
+		} else {
+			resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+		}
+		if !g.RetryPolicy.shouldRetry(r, resp, err, attempt) {
+			return resp, attempt, err
+		}
+
+		delay := g.RetryPolicy.backoff(attempt, resp)
+		if resp != nil {
+			// drain the body so that the connection can be reused
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempt, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func testRetryPolicy() *RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.MinBackoff = time.Millisecond
+	p.MaxBackoff = 5 * time.Millisecond
+	return p
+}
+
+func TestDoRetriesTransientStatus(t *testing.T) {
+	calls := 0
+	g := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"task_id": "abc"}`))
+	}), WithRetryPolicy(testRetryPolicy()))
+	_, resp, err := g.TaskDetails("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %v", resp.Attempts)
+	}
+}
+
+func TestDoRetryGivesUp(t *testing.T) {
+	calls := 0
+	g := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}), WithRetryPolicy(testRetryPolicy()))
+	_, resp, err := g.TaskDetails("abc")
+	if err == nil {
+		t.Error("Should have received error")
+	}
+	if calls != 4 || resp.Attempts != 4 {
+		t.Errorf("Expected 4 attempts, got %v calls and %v attempts", calls, resp.Attempts)
+	}
+}
+
+func TestDoDoesNotRetryPost(t *testing.T) {
+	calls := 0
+	g := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}), WithRetryPolicy(testRetryPolicy()))
+	req, err := g.NewRequest("POST", "api/v2/aoi/add", map[string]string{"name": "Foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, _ := g.Do(req, nil)
+	if calls != 1 || resp.Attempts != 1 {
+		t.Errorf("Expected a single attempt, got %v calls and %v attempts", calls, resp.Attempts)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", false, 0, 0},
+		{"bogus", false, 0, 0},
+		{"-1", false, 0, 0},
+		{"120", true, 120 * time.Second, 120 * time.Second},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), true, 58 * time.Second, time.Minute},
+	}
+	for _, tt := range tests {
+		d, ok := parseRetryAfter(tt.value)
+		if ok != tt.ok || d < tt.min || d > tt.max {
+			t.Errorf("parseRetryAfter(%q) = %v, %v", tt.value, d, ok)
+		}
+	}
+}
+
+func TestBackoffHonorsRetryAfter(t *testing.T) {
+	p := testRetryPolicy()
+	p.MaxBackoff = 5 * time.Second
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	if d := p.backoff(1, resp); d != 2*time.Second {
+		t.Errorf("Expected 2s, got %v", d)
+	}
+	if d := p.backoff(10, nil); d > p.MaxBackoff+time.Duration(p.Jitter*float64(p.MaxBackoff)) {
+		t.Errorf("Backoff %v exceeds maximum", d)
+	}
+}
+
+func TestDoReturnsLongRetryAfter(t *testing.T) {
+	calls := 0
+	g := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}), WithRetryPolicy(testRetryPolicy()))
+	_, resp, err := g.TaskDetails("abc")
+	if err == nil {
+		t.Error("Should have received error")
+	}
+	if calls != 1 || resp.Attempts != 1 {
+		t.Errorf("Expected a single attempt, got %v calls and %v attempts", calls, resp.Attempts)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TaskState is the state of a GRiD task, or of the export or TDA it produces.
+type TaskState string
+
+// The Celery task states reported by GRiD.
+const (
+	TaskPending  TaskState = "PENDING"  // waiting to run, or unknown
+	TaskReceived TaskState = "RECEIVED" // received by a worker
+	TaskStarted  TaskState = "STARTED"  // started by a worker
+	TaskRunning  TaskState = "RUNNING"  // in progress; used by GRiD's export tasks
+	TaskRetry    TaskState = "RETRY"    // failed, and waiting to be retried
+	TaskSuccess  TaskState = "SUCCESS"  // finished successfully
+	TaskFailure  TaskState = "FAILURE"  // finished with an error
+	TaskRevoked  TaskState = "REVOKED"  // canceled
+)
+
+// IsTerminal reports whether a task in state s has finished, successfully or
+// not.
+func (s TaskState) IsTerminal() bool {
+	return s == TaskSuccess || s == TaskFailure || s == TaskRevoked
+}
+
+// IsSuccess reports whether a task in state s has finished successfully.
+func (s TaskState) IsSuccess() bool {
+	return s == TaskSuccess
+}
+
+// Default polling schedule for WaitForTask.
+const (
+	defaultPollInterval    = 2 * time.Second
+	defaultMaxPollInterval = 30 * time.Second
+	defaultPollMultiplier  = 1.5
+)
+
+// ErrTaskFailed is returned, wrapped in a *TaskError, when a task fails or is
+// revoked.
+var ErrTaskFailed = errors.New("task failed")
+
+/*
+TaskError reports a task that finished without succeeding. Traceback holds the
+Python traceback reported by GRiD, if any.
+*/
+type TaskError struct {
+	TaskID    string
+	Name      string
+	State     TaskState
+	Traceback string
+}
+
+func (e *TaskError) Error() string {
+	msg := fmt.Sprintf("task %v", e.TaskID)
+	if e.Name != "" {
+		msg += fmt.Sprintf(" (%v)", e.Name)
+	}
+	msg += ": " + string(e.State)
+	// the last line of a traceback is the exception itself
+	lines := strings.Split(strings.TrimSpace(e.Traceback), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		msg += ": " + last
+	}
+	return msg
+}
+
+// Unwrap returns ErrTaskFailed.
+func (e *TaskError) Unwrap() error {
+	return ErrTaskFailed
+}
+
+// WaitOptions controls how WaitForTask polls a task. The zero value polls
+// every 2 seconds at first, backing off by half again each time to at most
+// every 30 seconds.
+type WaitOptions struct {
+	Interval    time.Duration // time before the first poll after the initial one
+	MaxInterval time.Duration // longest time between polls
+	Multiplier  float64       // growth of the interval after each poll; 1 means no growth
+
+	// Updates, if non-nil, is sent the task each time its state changes,
+	// starting with its state when first polled, and is closed when
+	// WaitForTask returns. WaitForTask blocks until each update is received.
+	Updates chan<- *TaskObject
+}
+
+// DefaultWaitOptions returns the polling schedule WaitForTask uses for options
+// left unset.
+func DefaultWaitOptions() *WaitOptions {
+	return &WaitOptions{
+		Interval:    defaultPollInterval,
+		MaxInterval: defaultMaxPollInterval,
+		Multiplier:  defaultPollMultiplier,
+	}
+}
+
+/*
+WaitForTask polls the task with the given ID until it finishes, and returns the
+task as last polled. If the task fails or is revoked, a *TaskError is returned
+along with the task. Errors from polling, other than those retried by the
+client's RetryPolicy, are returned immediately, as is ctx's error if it is
+canceled or its deadline expires. If opts is nil, the defaults are used.
+*/
+func (g *Grid) WaitForTask(ctx context.Context, taskID string, opts *WaitOptions) (*TaskObject, error) {
+	var o WaitOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Updates != nil {
+		defer close(o.Updates)
+	}
+	if o.Interval <= 0 {
+		o.Interval = defaultPollInterval
+	}
+	if o.MaxInterval <= 0 {
+		o.MaxInterval = defaultMaxPollInterval
+	}
+	if o.Multiplier < 1 {
+		o.Multiplier = defaultPollMultiplier
+	}
+
+	interval := o.Interval
+	var state TaskState
+	for {
+		task, _, err := g.TaskDetailsWithContext(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+
+		if task.State != state && o.Updates != nil {
+			select {
+			case o.Updates <- task:
+			case <-ctx.Done():
+				return task, ctx.Err()
+			}
+		}
+		state = task.State
+
+		if task.State.IsSuccess() {
+			return task, nil
+		}
+		if task.State.IsTerminal() {
+			return task, &TaskError{TaskID: taskID, Name: task.Name, State: task.State, Traceback: task.Traceback}
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return task, ctx.Err()
+		}
+		interval = time.Duration(float64(interval) * o.Multiplier)
+		if interval > o.MaxInterval {
+			interval = o.MaxInterval
+		}
+	}
+}
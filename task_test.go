@@ -0,0 +1,156 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTaskServer returns a client for a test server that reports each of the
+// given states for a task in turn, then the last state forever.
+func newTaskServer(t *testing.T, states ...string) *Grid {
+	var mu sync.Mutex
+	polls := 0
+	return newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/task/abc/" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		state := states[len(states)-1]
+		if polls < len(states) {
+			state = states[polls]
+		}
+		polls++
+		mu.Unlock()
+
+		traceback := ""
+		if state == "FAILURE" {
+			traceback = "Traceback (most recent call last):\n  File \"tasks.py\", line 1\nValueError: no points in AOI\n"
+		}
+		fmt.Fprintf(w, `{"task_id": "abc", "task_name": "export", "task_state": %q, "task_traceback": %q}`, state, traceback)
+	}))
+}
+
+func TestWaitForTask(t *testing.T) {
+	g := newTaskServer(t, "PENDING", "STARTED", "STARTED", "SUCCESS")
+
+	updates := make(chan *TaskObject)
+	var states []TaskState
+	done := make(chan struct{})
+	go func() {
+		for task := range updates {
+			states = append(states, task.State)
+		}
+		close(done)
+	}()
+
+	task, err := g.WaitForTask(context.Background(), "abc", &WaitOptions{Interval: time.Millisecond, Updates: updates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if task.State != TaskSuccess {
+		t.Errorf("Expected SUCCESS, got %v", task.State)
+	}
+	if fmt.Sprint(states) != "[PENDING STARTED SUCCESS]" {
+		t.Errorf("Unexpected state transitions %v", states)
+	}
+}
+
+func TestWaitForTaskFailure(t *testing.T) {
+	g := newTaskServer(t, "STARTED", "FAILURE")
+
+	task, err := g.WaitForTask(context.Background(), "abc", &WaitOptions{Interval: time.Millisecond})
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || !errors.Is(err, ErrTaskFailed) {
+		t.Fatalf("Expected a *TaskError, got %v", err)
+	}
+	if task == nil || taskErr.State != TaskFailure || taskErr.Traceback == "" {
+		t.Errorf("Unexpected task error %+v", taskErr)
+	}
+	if want := "task abc (export): FAILURE: ValueError: no points in AOI"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestWaitForTaskCanceled(t *testing.T) {
+	g := newTaskServer(t, "STARTED")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := g.WaitForTask(ctx, "abc", &WaitOptions{Interval: 10 * time.Millisecond, Multiplier: 1})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestWaitForTaskNotFound(t *testing.T) {
+	g := newTaskServer(t, "STARTED")
+
+	if _, err := g.WaitForTask(context.Background(), "xyz", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v, got %v", ErrNotFound, err)
+	}
+}
+
+func TestTaskState(t *testing.T) {
+	tests := []struct {
+		state             TaskState
+		terminal, success bool
+	}{
+		{TaskPending, false, false},
+		{TaskRunning, false, false},
+		{TaskRetry, false, false},
+		{TaskSuccess, true, true},
+		{TaskFailure, true, false},
+		{TaskRevoked, true, false},
+	}
+	for _, tt := range tests {
+		if tt.state.IsTerminal() != tt.terminal || tt.state.IsSuccess() != tt.success {
+			t.Errorf("%v: expected terminal %v and success %v", tt.state, tt.terminal, tt.success)
+		}
+	}
+}
+
+func TestTaskObjectTimestamp(t *testing.T) {
+	want := time.Date(2016, 4, 1, 15, 59, 0, 587000000, time.UTC)
+	for _, ts := range []string{"2016-04-01T15:59:00.587", "2016-04-01T15:59:00.587Z", "2016-04-01 15:59:00.587000+00:00"} {
+		var task TaskObject
+		if err := json.Unmarshal([]byte(`{"task_state": "SUCCESS", "task_tstamp": "`+ts+`"}`), &task); err != nil {
+			t.Fatal(err)
+		}
+		if !task.Timestamp.Equal(want) || task.State != TaskSuccess {
+			t.Errorf("%v: expected %v, got %v", ts, want, task.Timestamp)
+		}
+
+		data, _ := json.Marshal(task)
+		var again TaskObject
+		if err := json.Unmarshal(data, &again); err != nil || !again.Timestamp.Equal(want) {
+			t.Errorf("%v: did not round trip: %s", ts, data)
+		}
+	}
+
+	var task TaskObject
+	if err := json.Unmarshal([]byte(`{"task_tstamp": "yesterday"}`), &task); err == nil {
+		t.Error("Should have received error")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// timestampLayouts are the formats of the timestamps GRiD reports, with and
+// without fractional seconds. Timestamps without a time zone are in UTC.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseTimestamp parses a timestamp in any of the formats GRiD reports.
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse timestamp %q", s)
+}
+
+/*
+Time is a timestamp reported by GRiD. It unmarshals from any of the formats
+GRiD uses, and embeds a time.Time, so it may be compared and sorted directly.
+
+A Time remembers the text it was unmarshaled from, and marshals and prints it
+unchanged, so re-encoding a GRiD response does not alter its timestamps. Once
+the time is changed, it is marshaled in RFC 3339 format instead.
+*/
+type Time struct {
+	time.Time
+	raw string
+}
+
+// UnmarshalJSON parses a JSON string in any of the formats GRiD uses. Null and
+// the empty string give the zero Time.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*t = Time{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*t = Time{}
+		return nil
+	}
+	parsed, err := parseTimestamp(s)
+	if err != nil {
+		return err
+	}
+	*t = Time{Time: parsed, raw: s}
+	return nil
+}
+
+// MarshalJSON formats t as the text it was unmarshaled from, if it is
+// unchanged, or in RFC 3339 format. The zero Time is marshaled as null.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.String())
+}
+
+// String returns the text t was unmarshaled from, if it is unchanged, or t in
+// RFC 3339 format.
+func (t Time) String() string {
+	if t.raw != "" {
+		if parsed, err := parseTimestamp(t.raw); err == nil && parsed.Equal(t.Time) {
+			return t.raw
+		}
+	}
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
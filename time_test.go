@@ -0,0 +1,137 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timestampField matches the timestamps in a response fixture.
+var timestampField = regexp.MustCompile(`"(created_at|started_at|collected_at|task_tstamp)": "([^"]+)"`)
+
+// readFixture unmarshals the named response fixture into v, and returns the
+// timestamps it contains.
+func readFixture(t *testing.T, name string, v interface{}) []string {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%v: %v", name, err)
+	}
+	var timestamps []string
+	for _, m := range timestampField.FindAllStringSubmatch(string(data), -1) {
+		timestamps = append(timestamps, m[0])
+	}
+	return timestamps
+}
+
+func TestTimeFixtures(t *testing.T) {
+	fixtures := map[string]interface{}{
+		"aoi_list.json":      new(AOIArray),
+		"aoi_detail.json":    new(AOIDetail),
+		"export_detail.json": new(ExportDetail),
+		"task.json":          new(TaskObject),
+	}
+	for name, v := range fixtures {
+		timestamps := readFixture(t, name, v)
+		if len(timestamps) == 0 {
+			t.Fatalf("%v: no timestamps", name)
+		}
+
+		// every timestamp is marshaled back unchanged
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ts := range timestamps {
+			if !strings.Contains(string(data), ts) {
+				t.Errorf("%v: %v was not marshaled back unchanged", name, ts)
+			}
+		}
+	}
+}
+
+func TestTimeValues(t *testing.T) {
+	var aoi AOIDetail
+	readFixture(t, "aoi_detail.json", &aoi)
+	var export ExportDetail
+	readFixture(t, "export_detail.json", &export)
+	var task TaskObject
+	readFixture(t, "task.json", &task)
+
+	tests := []struct {
+		got  Time
+		want time.Time
+	}{
+		{aoi.CreatedAt, time.Date(2016, 4, 1, 15, 59, 0, 587000000, time.UTC)},
+		{aoi.ExportSet[0].StartedAt, time.Date(2016, 4, 1, 16, 1, 12, 119584000, time.UTC)},
+		{aoi.PointcloudIntersects[0].CollectedAt, time.Date(2012, 8, 14, 0, 0, 0, 0, time.UTC)},
+		{aoi.RasterIntersects[0].CollectedAt, time.Date(2012, 8, 14, 0, 0, 0, 0, time.UTC)},
+		{export.TDASet[0].CreatedAt, time.Date(2016, 4, 1, 16, 5, 48, 312000, time.UTC)},
+		{task.Timestamp, time.Date(2016, 4, 1, 16, 4, 39, 870245000, time.UTC)},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("Expected %v, got %v", tt.want, tt.got.Time)
+		}
+	}
+	if task.State != TaskSuccess || export.TDASet[0].Status != TaskSuccess {
+		t.Errorf("Unexpected states %v, %v", task.State, export.TDASet[0].Status)
+	}
+}
+
+func TestTimeSort(t *testing.T) {
+	var aois AOIArray
+	readFixture(t, "aoi_list.json", &aois)
+
+	list := aois.AOIList
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt.Time) })
+	if list[0].Name != "Bar" || list[1].Name != "Foo" {
+		t.Errorf("Expected Bar before Foo, got %v before %v", list[0].Name, list[1].Name)
+	}
+}
+
+func TestTimeJSON(t *testing.T) {
+	var v struct {
+		A Time `json:"a"`
+		B Time `json:"b"`
+		C Time `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": "2015-06-22T08:15:33.510", "b": null, "c": ""}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if !v.B.IsZero() || !v.C.IsZero() {
+		t.Errorf("Expected zero times, got %v, %v", v.B, v.C)
+	}
+
+	// a changed time is marshaled in RFC 3339 format
+	v.C.Time = v.A.Add(time.Hour)
+	data, _ := json.Marshal(v)
+	if want := `{"a":"2015-06-22T08:15:33.510","b":null,"c":"2015-06-22T09:15:33.51Z"}`; string(data) != want {
+		t.Errorf("Expected %v, got %s", want, data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"a": "June 22nd"}`), &v); err == nil {
+		t.Error("Should have received error")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+/*
+WithCAFile trusts the PEM-encoded CA certificates in the file at path, such as
+the DoD root CAs, in addition to the system roots.
+
+The TLS options (WithCAFile, WithMinTLSVersion and WithInsecureSkipVerify)
+configure the *http.Transport used by the client, on top of any TLS settings
+it already has. They cannot be combined with a custom http.RoundTripper of
+another type.
+*/
+func WithCAFile(path string) Option {
+	return func(g *Grid) error {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA file %v", path)
+		}
+		g.configureTLS(func(config *tls.Config) error {
+			config.RootCAs = pool
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version the client will negotiate,
+// for example tls.VersionTLS12.
+func WithMinTLSVersion(version uint16) Option {
+	return func(g *Grid) error {
+		g.configureTLS(func(config *tls.Config) error {
+			config.MinVersion = version
+			return nil
+		})
+		return nil
+	}
+}
+
+/*
+WithInsecureSkipVerify disables verification of the server's certificate
+chain and host name. This makes connections to GRiD vulnerable to
+interception, and should only be used for testing; a warning is logged
+whenever a client is created with it.
+*/
+func WithInsecureSkipVerify() Option {
+	return func(g *Grid) error {
+		g.configureTLS(func(config *tls.Config) error {
+			config.InsecureSkipVerify = true
+			return nil
+		})
+		return nil
+	}
+}
+
+// configureTLS records a change to the client's TLS configuration, made by
+// applyTLS once the transport is known.
+func (g *Grid) configureTLS(f func(*tls.Config) error) {
+	g.tlsOptions = append(g.tlsOptions, f)
+}
+
+/*
+applyTLS installs the client's TLS configuration, if any, on a copy of the
+transport the client would otherwise use. The options are applied in order to
+a copy of the transport's own TLS configuration, so that settings such as its
+RootCAs or ServerName are kept.
+*/
+func (g *Grid) applyTLS(client *http.Client) error {
+	if len(g.tlsOptions) == 0 {
+		return nil
+	}
+
+	rt := client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return errors.New("TLS options require the transport to be an *http.Transport")
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.TLSClientConfig != nil {
+		config = t.TLSClientConfig.Clone()
+	}
+	for _, f := range g.tlsOptions {
+		if err := f(config); err != nil {
+			return err
+		}
+	}
+	t = t.Clone()
+	t.TLSClientConfig = config
+	client.Transport = t
+	g.Transport = t
+
+	if config.InsecureSkipVerify {
+		log.Printf("WARNING: TLS certificate verification is disabled for GRiD at %v. "+
+			"Connections are not secure and may be intercepted.", g.BaseURL)
+	}
+	return nil
+}
+
+// parseTLSVersion parses a TLS version as written in the config file, such as
+// "1.2".
+func parseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version %q", v)
+}
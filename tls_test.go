@@ -0,0 +1,108 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func newTLSTestServer(t *testing.T) (*httptest.Server, string) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"task_id": "abc"}`))
+	}))
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return ts, caFile
+}
+
+func TestTLSVerification(t *testing.T) {
+	ts, caFile := newTLSTestServer(t)
+	defer ts.Close()
+
+	g, err := NewClient(WithBaseURL(ts.URL), WithRetryPolicy(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.TaskDetails("abc"); err == nil {
+		t.Error("Expected an untrusted certificate to be rejected")
+	}
+
+	g, err = NewClient(WithBaseURL(ts.URL), WithCAFile(caFile), WithMinTLSVersion(tls.VersionTLS12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.TaskDetails("abc"); err != nil {
+		t.Error(err)
+	}
+
+	g, err = NewClient(WithBaseURL(ts.URL), WithInsecureSkipVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.TaskDetails("abc"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTLSOptionsKeepTransportConfig(t *testing.T) {
+	ts, _ := newTLSTestServer(t)
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}
+	g, err := NewClient(WithBaseURL(ts.URL), WithTransport(transport), WithMinTLSVersion(tls.VersionTLS13))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.TaskDetails("abc"); err != nil {
+		t.Error(err)
+	}
+	config := g.Transport.(*http.Transport).TLSClientConfig
+	if config.RootCAs != roots || config.MinVersion != tls.VersionTLS13 {
+		t.Errorf("Expected the transport's roots and the minimum version, got %+v", config)
+	}
+	if transport.TLSClientConfig.MinVersion != 0 {
+		t.Error("Expected the given transport to be left unchanged")
+	}
+}
+
+func TestWithCAFileInvalid(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ioutil.WriteFile(caFile, []byte("not a certificate"), 0600)
+	if _, err := NewClient(WithCAFile(caFile)); err == nil {
+		t.Error("Should have received error")
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	if v, err := parseTLSVersion("1.3"); err != nil || v != tls.VersionTLS13 {
+		t.Errorf("parseTLSVersion(\"1.3\") = %v, %v", v, err)
+	}
+	if _, err := parseTLSVersion("1.4"); err == nil {
+		t.Error("Should have received error")
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ManifestName is the name of the manifest of SHA-256 checksums written to
+// each download directory by clients created with WithSHA256Manifest. It is in
+// the format of the sha256sum tool, so it may be checked with sha256sum -c.
+const ManifestName = "SHA256SUMS"
+
+// Errors returned, wrapped in an *IntegrityError, when a file does not match
+// what the server or the manifest says it should be.
+var (
+	ErrIncomplete       = errors.New("incomplete download")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+/*
+IntegrityError reports a downloaded file whose size or checksum does not match
+the Content-Length, Digest, Repr-Digest or Content-MD5 header sent by the
+server, or the manifest it was verified against. It unwraps to ErrIncomplete
+or ErrChecksumMismatch.
+*/
+type IntegrityError struct {
+	Name      string // name of the file
+	Algorithm string // "size", or the checksum algorithm, such as "sha-256"
+	Want      string // expected size or checksum
+	Got       string // actual size or checksum
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("%v: %v mismatch: expected %v, got %v", e.Name, e.Algorithm, e.Want, e.Got)
+}
+
+// Unwrap returns ErrIncomplete for a size mismatch, and ErrChecksumMismatch
+// otherwise.
+func (e *IntegrityError) Unwrap() error {
+	if e.Algorithm == "size" {
+		return ErrIncomplete
+	}
+	return ErrChecksumMismatch
+}
+
+/*
+WithSHA256Manifest computes the SHA-256 checksum of each file downloaded,
+reporting it in FileInfo.SHA256. Files saved by DownloadToDir or a
+DownloadManager are also recorded in the ManifestName file in their directory,
+to be checked later with VerifyManifest.
+*/
+func WithSHA256Manifest() Option {
+	return func(g *Grid) error {
+		g.manifest = true
+		return nil
+	}
+}
+
+// newHash returns a hash for the named digest algorithm, or nil if the
+// algorithm is not supported.
+func newHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "sha-256":
+		return sha256.New()
+	case "sha-512":
+		return sha512.New()
+	case "md5":
+		return md5.New()
+	}
+	return nil
+}
+
+/*
+integrityCheck checks a download against the size and checksums given by the
+server, computing the checksums as the file is written through it.
+*/
+type integrityCheck struct {
+	name   string
+	size   int64             // expected size, or -1 if unknown
+	want   map[string][]byte // expected checksums by algorithm
+	hashes map[string]hash.Hash
+}
+
+/*
+newIntegrityCheck returns a check of the file described by resp, expected to be
+size bytes long in total. The Digest and Repr-Digest headers describe the
+whole file, but Content-MD5 only describes the body, so it is only used if
+whole is true. The SHA-256 checksum is always computed if withSHA256 is
+true.
+*/
+func newIntegrityCheck(name string, resp *http.Response, size int64, whole, withSHA256 bool) *integrityCheck {
+	c := &integrityCheck{
+		name:   name,
+		size:   size,
+		want:   map[string][]byte{},
+		hashes: map[string]hash.Hash{},
+	}
+
+	// Digest: sha-256=<base64>, md5=<base64> (RFC 3230)
+	for _, v := range resp.Header.Values("Digest") {
+		for _, d := range strings.Split(v, ",") {
+			if alg, sum, ok := strings.Cut(strings.TrimSpace(d), "="); ok {
+				c.addWant(strings.ToLower(alg), sum)
+			}
+		}
+	}
+	// Repr-Digest: sha-256=:<base64>: (RFC 9530)
+	for _, v := range resp.Header.Values("Repr-Digest") {
+		for _, d := range strings.Split(v, ",") {
+			if alg, sum, ok := strings.Cut(strings.TrimSpace(d), "="); ok {
+				c.addWant(strings.ToLower(alg), strings.Trim(sum, ":"))
+			}
+		}
+	}
+	if v := resp.Header.Get("Content-MD5"); v != "" && whole {
+		c.addWant("md5", v)
+	}
+
+	if withSHA256 {
+		c.hashes["sha-256"] = newHash("sha-256")
+	}
+	return c
+}
+
+func (c *integrityCheck) addWant(algorithm, sum string) {
+	h := newHash(algorithm)
+	if h == nil {
+		return
+	}
+	b, err := base64.StdEncoding.DecodeString(sum)
+	if err != nil {
+		return
+	}
+	c.want[algorithm] = b
+	if c.hashes[algorithm] == nil {
+		c.hashes[algorithm] = h
+	}
+}
+
+// needed reports whether anything written must be hashed.
+func (c *integrityCheck) needed() bool {
+	return len(c.hashes) > 0
+}
+
+// Write adds p to the checksums.
+func (c *integrityCheck) Write(p []byte) (int, error) {
+	for _, h := range c.hashes {
+		h.Write(p)
+	}
+	return len(p), nil
+}
+
+// verify checks a download of n bytes in total.
+func (c *integrityCheck) verify(n int64) error {
+	if c.size >= 0 && n != c.size {
+		return &IntegrityError{Name: c.name, Algorithm: "size", Want: fmt.Sprint(c.size), Got: fmt.Sprint(n)}
+	}
+	for alg, want := range c.want {
+		if got := c.hashes[alg].Sum(nil); !bytes.Equal(got, want) {
+			return &IntegrityError{Name: c.name, Algorithm: alg, Want: hex.EncodeToString(want), Got: hex.EncodeToString(got)}
+		}
+	}
+	return nil
+}
+
+// sha256 returns the hex-encoded SHA-256 checksum, if computed.
+func (c *integrityCheck) sha256() string {
+	if h := c.hashes["sha-256"]; h != nil {
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	return ""
+}
+
+// manifestMu serializes updates to manifests, which may be shared by
+// concurrent downloads into the same directory.
+var manifestMu sync.Mutex
+
+// readManifest reads the checksums by file name from the manifest in dir.
+func readManifest(dir string) (map[string]string, error) {
+	f, err := os.Open(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		// "<checksum>  <name>", or "<checksum> *<name>" in binary mode
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok || len(name) < 2 {
+			return nil, fmt.Errorf("invalid line in %v: %q", ManifestName, line)
+		}
+		sums[name[1:]] = sum
+	}
+	return sums, scanner.Err()
+}
+
+// addToManifest records the checksum of the named file in the manifest in dir.
+func addToManifest(dir, name, sum string) error {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	sums, err := readManifest(dir)
+	if os.IsNotExist(err) {
+		sums, err = map[string]string{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	sums[name] = sum
+
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%v  %v\n", sums[name], name)
+	}
+
+	return writeFileAtomic(dir, ManifestName, buf.Bytes())
+}
+
+// writeFileAtomic writes data to the named file in dir by way of a temporary
+// file, so a crash cannot leave it truncated.
+func writeFileAtomic(dir, name string, data []byte) error {
+	tmp, err := ioutil.TempFile(dir, "."+name+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	os.Chmod(tmp.Name(), 0644)
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// VerifyResult is the outcome of verifying a single file.
+type VerifyResult struct {
+	Name string // name of the file, relative to the directory
+	Err  error  // why the file failed verification, if it did
+}
+
+/*
+VerifyManifest checks each file listed in the manifest in dir against its
+recorded SHA-256 checksum, returning a result for each file in order of
+name. A file that has changed fails with an *IntegrityError, and one that is
+missing with an error satisfying os.IsNotExist. An error is returned only if
+the manifest cannot be read.
+*/
+func VerifyManifest(dir string) ([]VerifyResult, error) {
+	sums, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]VerifyResult, len(names))
+	for i, name := range names {
+		results[i] = VerifyResult{Name: name, Err: verifyFile(filepath.Join(dir, name), name, sums[name])}
+	}
+	return results, nil
+}
+
+func verifyFile(path, name, want string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, want) {
+		return &IntegrityError{Name: name, Algorithm: "sha-256", Want: want, Got: got}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2016, RadiantBlue Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newDigestServer returns a client for a test server that serves content, with
+// support for Range requests, and the given checksum header.
+func newDigestServer(t *testing.T, content []byte, header, value string, opts ...Option) *Grid {
+	return newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="file.zip"`)
+		w.Header().Set(header, value)
+		http.ServeContent(w, r, "file.zip", time.Time{}, bytes.NewReader(content))
+	}), opts...)
+}
+
+func TestDownloadChecksums(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	sha := sha256.Sum256(content)
+	sum := md5.Sum(content)
+	good := base64.StdEncoding.EncodeToString(sha[:])
+	bad := base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	tests := []struct {
+		header, value string
+		err           error
+	}{
+		{"Digest", "SHA-256=" + good, nil},
+		{"Digest", "unixsum=30637, sha-256=" + bad, ErrChecksumMismatch},
+		{"Repr-Digest", "sha-256=:" + good + ":", nil},
+		{"Repr-Digest", "sha-256=:" + bad + ":", ErrChecksumMismatch},
+		{"Content-MD5", base64.StdEncoding.EncodeToString(sum[:]), nil},
+		{"Content-MD5", base64.StdEncoding.EncodeToString(make([]byte, 16)), ErrChecksumMismatch},
+	}
+	for _, tt := range tests {
+		g := newDigestServer(t, content, tt.header, tt.value)
+
+		var buf bytes.Buffer
+		if _, err := g.Download(context.Background(), 3, &buf); !errors.Is(err, tt.err) {
+			t.Errorf("%v: %v: expected %v, got %v", tt.header, tt.value, tt.err, err)
+		}
+
+		dir := t.TempDir()
+		if _, err := g.DownloadToDir(context.Background(), 3, dir); !errors.Is(err, tt.err) {
+			t.Errorf("%v: %v: expected %v, got %v", tt.header, tt.value, tt.err, err)
+		}
+		if files, _ := ioutil.ReadDir(dir); tt.err != nil && len(files) != 0 {
+			t.Errorf("Expected the corrupt file to be removed, got %v files", len(files))
+		}
+	}
+}
+
+func TestDownloadChecksumResumed(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	sha := sha256.Sum256(content)
+	g := newDigestServer(t, content, "Digest", "sha-256="+base64.StdEncoding.EncodeToString(sha[:]), WithSHA256Manifest())
+
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, ".grid-3.part"), content[:100], 0644)
+	info, err := g.DownloadToDir(context.Background(), 3, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Resumed != 100 || info.SHA256 != hex.EncodeToString(sha[:]) {
+		t.Errorf("Unexpected file info %+v", info)
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	g := newDownloadServer(t, "file-%d.zip")
+	g.manifest = true
+	dir := t.TempDir()
+
+	m := &DownloadManager{Grid: g, Dir: dir}
+	if _, err := m.Download(context.Background(), []int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, ManifestName))
+	sha := sha256.Sum256(bytes.Repeat([]byte{1}, 1001))
+	if !strings.HasPrefix(string(data), hex.EncodeToString(sha[:])+"  file-1.zip\n") {
+		t.Errorf("Unexpected manifest %q", data)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "file-2.zip"), []byte("tampered"), 0644)
+	os.Remove(filepath.Join(dir, "file-3.zip"))
+
+	results, err := VerifyManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[0].Name != "file-1.zip" || results[0].Err != nil {
+		t.Fatalf("Unexpected results %+v", results)
+	}
+	if !errors.Is(results[1].Err, ErrChecksumMismatch) {
+		t.Errorf("Expected %v, got %v", ErrChecksumMismatch, results[1].Err)
+	}
+	if !os.IsNotExist(results[2].Err) {
+		t.Errorf("Expected a missing file, got %v", results[2].Err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	for _, data := range []string{"first\n", "second\n"} {
+		if err := writeFileAtomic(dir, "out.txt", []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := ioutil.ReadFile(filepath.Join(dir, "out.txt")); string(got) != data {
+			t.Errorf("Expected %q, got %q", data, got)
+		}
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Expected only out.txt, got %v files", len(files))
+	}
+	if runtime.GOOS != "windows" && files[0].Mode().Perm() != 0644 {
+		t.Errorf("Expected mode 0644, got %v", files[0].Mode().Perm())
+	}
+}